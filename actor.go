@@ -1,12 +1,53 @@
 package goactor
 
 import (
-	queue "github.com/scryner/lfreequeue"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Event struct {
 	event        interface{}
 	responseChan chan<- interface{}
+	ctx          context.Context
+
+	sender        string
+	correlationID uint64
+	replied       int32
+	sentAt        time.Time
+	// timeout is the one the require caller waits for, 0 if none but ctx
+	timeout time.Duration
+	// origin is the event a forwarded one was made from, the reply state
+	// lives there since the caller waits on it
+	origin *Event
+	// chain holds the actors blocked on this event, the oldest caller first
+	chain []*innerActor
+	// replayed is set on a dead letter sent again
+	replayed bool
+}
+
+const (
+	replyPending int32 = iota
+	replySent
+	replyAbandoned
+)
+
+// respond answers the require caller at most once, so a response is never
+// blocked on the single slot of responseChan
+func (event *Event) respond(response interface{}) bool {
+	if event.responseChan == nil || !atomic.CompareAndSwapInt32(event.state(), replyPending, replySent) {
+		return false
+	}
+	event.responseChan <- response
+	return true
+}
+
+func (event *Event) state() *int32 {
+	for event.origin != nil {
+		event = event.origin
+	}
+	return &event.replied
 }
 
 type EventType int
@@ -22,46 +63,317 @@ type ActorInterface interface {
 	OnPullout(system *ActorSystem)
 }
 
+// ContextActorInterface is picked over Receive when implemented, ctx is the
+// one given to RequireCtx/RequestCtx or context.Background() otherwise
+type ContextActorInterface interface {
+	ActorInterface
+	ReceiveContext(ctx context.Context, system *ActorSystem, eventType EventType, event interface{}) interface{}
+}
+
 type innerActor struct {
+	id uint64
+	// actorImpl identifies the instance and is guarded by system.lock,
+	// running is the implementation the loop is currently driving
 	actorImpl  ActorInterface
+	running    ActorInterface
 	notifyChan chan interface{}
-	events     *queue.Queue
+	events     mailboxQueue
+	control    eventLane
 
 	name   string
 	system *ActorSystem
+
+	restarts restartWindow
+
+	size        int32
+	closed      int32
+	capacity    int
+	overflow    OverflowPolicy
+	mailboxLock sync.Mutex
+	notFull     *sync.Cond
+	dequeueLock sync.Mutex
+
+	stopped chan struct{}
+	// discarded holds the events left on exit, read once stopped
+	discarded []interface{}
+
+	weight int
+	// accepts holds the []reflect.Type of a TypeAcceptor, read while routing
+	accepts atomic.Value
+
+	swap *swapRequest
+
+	behaviors []Behavior
+	stash     eventLane
+	unstashed eventLane
+
+	dispatcher Dispatcher
+	plugged    bool
+	scheduled  int32
+	// current holds the *Event being processed, nil between events
+	current atomic.Value
+}
+
+type ActorOption func(actor *innerActor)
+
+func (actor *innerActor) push(event *Event) error {
+	if isSystemMessage(event.event) {
+		actor.signal(event)
+		return nil
+	}
+	return actor.enqueue(event)
+}
+
+// signal puts event on the system lane, which is drained before any user
+// event
+func (actor *innerActor) signal(event *Event) {
+	actor.control.push(event)
+	actor.wake()
 }
 
-func (actor *innerActor) push(event *Event) {
+// enqueue puts event on the user lane, behind what is already queued
+func (actor *innerActor) enqueue(event *Event) error {
+	if _, ok := event.event.(ExitEvent); !ok && actor.capacity > 0 {
+		if err := actor.reserve(event); err != nil {
+			return err
+		}
+	} else {
+		atomic.AddInt32(&actor.size, 1)
+	}
+
 	actor.events.Enqueue(event)
-	select {
-	case actor.notifyChan <- nil:
-	default:
+	actor.wake()
+	return nil
+}
+
+func (actor *innerActor) pop() (*Event, bool) {
+	if event, ok := actor.control.pop(); ok {
+		return event, true
+	}
+	if event, ok := actor.unstashed.pop(); ok {
+		return event, true
+	}
+	return actor.popEvent()
+}
+
+func (actor *innerActor) popEvent() (*Event, bool) {
+	if actor.dropsOldest() {
+		// the actor and senders making room take turns
+		actor.dequeueLock.Lock()
+		defer actor.dequeueLock.Unlock()
+	}
+	if event, ok := actor.events.Dequeue(); ok {
+		actor.release()
+		return event.(*Event), true
 	}
+	return nil, false
 }
 
+// evictEvent takes the event to drop for room out of the mailbox, the
+// oldest one unless the mailbox tells otherwise
+func (actor *innerActor) evictEvent() (*Event, bool) {
+	queue, ok := actor.events.(evictingQueue)
+	if !ok {
+		return actor.popEvent()
+	}
+	actor.dequeueLock.Lock()
+	defer actor.dequeueLock.Unlock()
+	if event, ok := queue.Evict(); ok {
+		actor.release()
+		return event.(*Event), true
+	}
+	return nil, false
+}
+
+func (actor *innerActor) processing() *Event {
+	event, _ := actor.current.Load().(*Event)
+	return event
+}
+
+// loop drives the actor on a goroutine of its own
 func (actor *innerActor) loop() {
-	actor.actorImpl.OnPlugin(actor.system)
-	defer actor.actorImpl.OnPullout(actor.system)
+	actor.plugin()
 	for {
 		<-actor.notifyChan
+		if actor.run(0) {
+			return
+		}
+	}
+}
 
-		for {
-			if event, ok := actor.events.Dequeue(); ok {
-				typedEvent := event.(*Event)
+func (actor *innerActor) plugin() {
+	if actor.plugged {
+		return
+	}
+	actor.plugged = true
+	if actor.running == nil {
+		actor.running = actor.actorImpl
+	}
+	actor.running.OnPlugin(actor.system)
+}
 
-				if _, ok := typedEvent.event.(ExitEvent); ok {
-					// TODO: custom exiting!
-					return
-				}
+// run processes at most throughput events, or until the mailbox is empty
+// for 0. It tells whether the actor has stopped.
+func (actor *innerActor) run(throughput int) (stopped bool) {
+	actor.plugin()
+	for processed := 0; throughput <= 0 || processed < throughput; processed++ {
+		actor.applySwap()
+		typedEvent, ok := actor.pop()
+		if !ok {
+			return false
+		}
 
-				if typedEvent.responseChan == nil {
-					actor.actorImpl.Receive(actor.system, EVENT_REQUEST, typedEvent.event)
-				} else {
-					typedEvent.responseChan <- actor.actorImpl.Receive(actor.system, EVENT_REQUIRE, typedEvent.event)
-				}
+		if exit, ok := typedEvent.event.(ExitEvent); ok {
+			// TODO: custom exiting!
+			// the exit overtook these, nobody is going to handle them
+			if TerminationReason(exit) == TERMINATED_SHUTDOWN {
+				actor.discarded = actor.discard(DEAD_LETTER_SHUTDOWN, ErrSystemShutdown)
 			} else {
-				break
+				actor.discarded = actor.discard(DEAD_LETTER_STOPPED, ErrActorStopped)
 			}
+			actor.stop(TerminationReason(exit))
+			return true
+		}
+
+		if !actor.process(typedEvent) {
+			actor.drain()
+			actor.stop(TERMINATED_CRASHED)
+			return true
 		}
 	}
+	return false
+}
+
+func (actor *innerActor) stop(reason TerminationReason) {
+	defer actor.finish(reason)
+	defer actor.running.OnPullout(actor.system)
+	actor.close()
+}
+
+// pending tells whether there is anything for run to do
+func (actor *innerActor) pending() bool {
+	if atomic.LoadInt32(&actor.size) > 0 || !actor.control.empty() || !actor.unstashed.empty() {
+		return true
+	}
+	actor.mailboxLock.Lock()
+	defer actor.mailboxLock.Unlock()
+	return actor.swap != nil
+}
+
+func (actor *innerActor) wake() {
+	if actor.dispatcher != nil {
+		actor.dispatcher.Notify(actor)
+		return
+	}
+	select {
+	case actor.notifyChan <- nil:
+	default:
+	}
+}
+
+func (actor *innerActor) process(event *Event) (alive bool) {
+	actor.current.Store(event)
+	defer func() {
+		actor.current.Store((*Event)(nil))
+		if reason := recover(); reason != nil {
+			alive = actor.supervise(event, reason)
+		}
+	}()
+
+	if event.ctx != nil && event.ctx.Err() != nil {
+		// caller has gone away, nobody cares about this event any more
+		code := DEAD_LETTER_CANCELLED
+		if event.ctx.Err() == context.DeadlineExceeded {
+			code = DEAD_LETTER_TIMEOUT
+		}
+		actor.system.deadLetter(actor.name, event, code, event.ctx.Err())
+		return true
+	}
+
+	if actor.system.skipping(event) {
+		actor.system.deadLetter(actor.name, event, DEAD_LETTER_TIMEOUT, ErrRequireAbandoned)
+		return true
+	}
+
+	if behavior := actor.behavior(); behavior != nil {
+		behavior(actor.message(event))
+	} else if impl, ok := actor.running.(MessageActorInterface); ok {
+		impl.ReceiveMessage(actor.message(event))
+	} else if event.responseChan == nil {
+		actor.receive(EVENT_REQUEST, event)
+	} else {
+		actor.system.reply(actor.name, event, actor.receive(EVENT_REQUIRE, event))
+	}
+	return true
+}
+
+func (actor *innerActor) receive(eventType EventType, event *Event) interface{} {
+	if impl, ok := actor.running.(ContextActorInterface); ok {
+		ctx := event.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return impl.ReceiveContext(withCallers(ctx, actor.callers(event)), actor.system, eventType, event.event)
+	}
+	return actor.running.Receive(actor.system, eventType, event.event)
+}
+
+func (actor *innerActor) supervise(event *Event, reason interface{}) bool {
+	crash := &ActorCrashError{actor.name, reason}
+	event.respond(&failedResponse{crash})
+	actor.system.deadLetter(actor.name, event, DEAD_LETTER_PANIC, crash)
+
+	directive := actor.system.decide(actor.name, reason, &actor.restarts)
+	actor.system.reportCrash(actor.name, event.event, reason, directive)
+
+	switch directive {
+	case DIRECTIVE_RESUME:
+		return true
+	case DIRECTIVE_RESTART:
+		actor.reset()
+		actor.running.OnPullout(actor.system)
+		actor.running.OnPlugin(actor.system)
+		return true
+	case DIRECTIVE_ESCALATE:
+		actor.system.Shutdown()
+		return false
+	default:
+		actor.system.detach(actor)
+		return false
+	}
+}
+
+// drain fails everything still queued on a stopped actor
+func (actor *innerActor) drain() {
+	actor.discard(DEAD_LETTER_PANIC, &ActorCrashError{actor.name, "actor stopped"})
+}
+
+// discard empties the mailbox into dead letters, failing require callers
+// with reason
+func (actor *innerActor) discard(code DeadLetterReason, reason error) (discarded []interface{}) {
+	actor.unstashAll()
+	for {
+		typedEvent, ok := actor.pop()
+		if !ok {
+			return discarded
+		}
+
+		if _, ok := typedEvent.event.(ExitEvent); ok {
+			continue
+		}
+
+		discarded = append(discarded, typedEvent.event)
+		typedEvent.respond(&failedResponse{reason})
+		actor.system.deadLetter(actor.name, typedEvent, code, reason)
+	}
+}
+
+func (actor *innerActor) finish(reason TerminationReason) {
+	actor.dropSwap()
+	if actor.system != nil {
+		actor.system.terminated(actor, reason)
+	}
+	if actor.stopped != nil {
+		close(actor.stopped)
+	}
 }
@@ -1,9 +1,11 @@
 package goactor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	queue "github.com/scryner/lfreequeue"
@@ -11,133 +13,525 @@ import (
 
 type ExitEvent int
 
+var actorSeq uint64
+
+var ErrSystemShutdown = errors.New("actor system is shutting down")
+
+type DiscardedMessage struct {
+	ActorName string
+	Event     interface{}
+}
+
+type ShutdownReport struct {
+	Unfinished []string
+	Discarded  []*DiscardedMessage
+}
+
+// ActorSystem is a handle on the state of a system. Every actor is given one
+// of its own, so the requires made through it tell which actor waits.
 type ActorSystem struct {
+	*systemState
+	// caller is the actor the handle was given to, nil for the system's own
+	caller *innerActor
+}
+
+type systemState struct {
 	router              Router
 	deadLetterProcessor DeadLetterProcessor
-	actors              map[string][]*innerActor
+	registry            *actorRegistry
 	lock                *sync.RWMutex
-}
 
-func (system *ActorSystem) AddActor(name string, actorImpl ActorInterface) (ok bool, err error) {
-	system.lock.Lock()
+	crashReporter   CrashReporter
+	defaultStrategy *SupervisorStrategy
+	strategies      map[string]*SupervisorStrategy
+
+	stopping int32
 
+	alive    map[string]int
+	watchers map[string]map[string]bool
+	// instances ShutdownGracefully took out of the registry, by name, still
+	// told of what they watch while they drain
+	draining map[string][]*innerActor
+
+	schedules map[string]map[*Schedule]struct{}
+
+	stream *eventStream
+
+	abandoned     uint64
+	orphaned      uint64
+	skipAbandoned int32
+
+	defaultDispatcher Dispatcher
+	dispatchers       map[string]Dispatcher
+
+	debugWaits int32
+	waits      *waitsFor
+
+	remotes      map[string]*remoteNode
+	remoteSecret []byte
+	serializers  *SerializerRegistry
+}
+
+func (system *ActorSystem) AddActor(name string, actorImpl ActorInterface, options ...ActorOption) (ok bool, err error) {
 	actor := &innerActor{
+		id:         atomic.AddUint64(&actorSeq, 1),
 		actorImpl:  actorImpl,
+		running:    actorImpl,
 		notifyChan: make(chan interface{}, 1),
 		events:     queue.NewQueue(),
 		name:       name,
-		system:     system,
+		stopped:    make(chan struct{}),
+	}
+	actor.system = &ActorSystem{system.systemState, actor}
+	actor.notFull = sync.NewCond(&actor.mailboxLock)
+	actor.accept(actorImpl)
+	for _, option := range options {
+		option(actor)
 	}
 
-	if actors, ok := system.actors[name]; ok {
-		system.actors[name] = append(actors, actor)
-	} else {
-		actors = make([]*innerActor, 1)
-		actors[0] = actor
-		system.actors[name] = actors
+	system.lock.Lock()
+	if atomic.LoadInt32(&system.stopping) != 0 {
+		system.lock.Unlock()
+		return false, ErrSystemShutdown
+	}
+
+	actor.dispatcher = system.dispatchers[name]
+	if actor.dispatcher == nil {
+		actor.dispatcher = system.defaultDispatcher
 	}
+	system.alive[name]++
+	actors := system.registry.lookup(name)
+	system.registry.publish(name, append(actors[:len(actors):len(actors)], actor))
 
 	system.lock.Unlock()
-	go actor.loop()
+	actor.dispatcher.Dispatch(actor)
 	return true, nil
 }
 
 func (system *ActorSystem) RemoveActor(name string, actorImpl ActorInterface) (ok bool, err error) {
 	system.lock.Lock()
 	defer system.lock.Unlock()
-	if actors, ok := system.actors[name]; ok {
-		if len(actors) == 1 && actors[0].actorImpl == actorImpl {
-			actors[0].push(&Event{
-				event:        ExitEvent(0),
-				responseChan: nil,
-			})
-			delete(system.actors, name)
-		} else {
-			index := -1
-			for i, actress := range actors {
-				if actress.actorImpl == actorImpl {
-					index = i
-					break
-				}
-			}
-			if index >= 0 {
-				actors[index].push(&Event{
-					event:        ExitEvent(0),
-					responseChan: nil,
-				})
-				system.actors[name] = append(actors[:index], actors[index+1:]...)
-				return true, nil
-			}
+	actors := system.registry.lookup(name)
+	for i, actor := range actors {
+		if actor.actorImpl != actorImpl {
+			continue
+		}
+
+		actor.push(&Event{
+			event:        ExitEvent(TERMINATED_REMOVED),
+			responseChan: nil,
+		})
+		system.stream.forget(actor)
+		system.registry.publish(name, without(actors, i))
+		if len(actors) == 1 {
+			system.cancelSchedules(name)
+			system.unwatchAll(name)
+			forget(system.router, name)
 		}
+		return true, nil
 	}
 	return false, errors.New("actor not in system")
 }
 
+// Shutdown tells every actor to stop after its current event, the system
+// accepts neither messages nor actors any more. Watchers stop along, they get
+// no Terminated for the others.
 func (system *ActorSystem) Shutdown() (ok bool, err error) {
 	system.lock.Lock()
 	defer system.lock.Unlock()
-	for _, actors := range system.actors {
-		for _, actor := range actors {
-			actor.push(&Event{
-				event:        ExitEvent(0),
+	atomic.StoreInt32(&system.stopping, 1)
+	// force clean
+	for _, actor := range system.registry.clear() {
+		actor.push(&Event{
+			event:        ExitEvent(TERMINATED_SHUTDOWN),
+			responseChan: nil,
+		})
+		forget(system.router, actor.name)
+	}
+	system.closeDispatchers()
+	for name := range system.schedules {
+		system.cancelSchedules(name)
+	}
+	system.stream.clear()
+	return
+}
+
+// ShutdownGracefully stops accepting messages for good, lets every actor
+// work off its mailbox and waits for all OnPullout. An actor still busy when
+// ctx is done is listed as unfinished and told to stop right after its
+// current event, without waiting for it. What is left in its mailbox then
+// goes to dead letters, the report only has the messages discarded by actors
+// stopped by the time it returns.
+func (system *ActorSystem) ShutdownGracefully(ctx context.Context) (report *ShutdownReport, err error) {
+	system.lock.Lock()
+	atomic.StoreInt32(&system.stopping, 1)
+	all := system.registry.clear()
+	for _, actor := range all {
+		system.draining[actor.name] = append(system.draining[actor.name], actor)
+		forget(system.router, actor.name)
+	}
+	for name := range system.schedules {
+		system.cancelSchedules(name)
+	}
+	system.stream.clear()
+	system.lock.Unlock()
+
+	for _, actor := range all {
+		// behind the mailbox rather than the system lane, to work it off first
+		actor.enqueue(&Event{
+			event:        ExitEvent(TERMINATED_SHUTDOWN),
+			responseChan: nil,
+		})
+	}
+	system.lock.Lock()
+	system.closeDispatchers()
+	system.lock.Unlock()
+
+	report = &ShutdownReport{}
+wait:
+	for _, actor := range all {
+		select {
+		case <-actor.stopped:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break wait
+		}
+	}
+	if err == nil {
+		return report, nil
+	}
+
+	for _, actor := range all {
+		select {
+		case <-actor.stopped:
+		default:
+			report.Unfinished = append(report.Unfinished, actor.name)
+			// overtakes the mailbox, which the actor discards itself
+			actor.signal(&Event{
+				event:        ExitEvent(TERMINATED_SHUTDOWN),
 				responseChan: nil,
 			})
 		}
 	}
-	// force clean
-	system.actors = make(map[string][]*innerActor)
-	return
+	for _, actor := range all {
+		select {
+		case <-actor.stopped:
+			for _, event := range actor.discarded {
+				report.Discarded = append(report.Discarded, &DiscardedMessage{actor.name, event})
+			}
+		default:
+		}
+	}
+	return report, err
 }
 
-func (system *ActorSystem) route(actorName string) (actor *innerActor, err error) {
+func (system *ActorSystem) detach(actor *innerActor) bool {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	actors := system.registry.lookup(actor.name)
+	for i, actress := range actors {
+		if actress == actor {
+			system.stream.forget(actor)
+			system.registry.publish(actor.name, without(actors, i))
+			if len(actors) == 1 {
+				system.cancelSchedules(actor.name)
+				system.unwatchAll(actor.name)
+				forget(system.router, actor.name)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (system *ActorSystem) Supervise(name string, strategy *SupervisorStrategy) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	if strategy == nil {
+		delete(system.strategies, name)
+	} else {
+		system.strategies[name] = strategy
+	}
+}
+
+func (system *ActorSystem) SetDefaultStrategy(strategy *SupervisorStrategy) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	system.defaultStrategy = strategy
+}
+
+func (system *ActorSystem) SetCrashReporter(reporter CrashReporter) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	system.crashReporter = reporter
+}
+
+func (system *ActorSystem) reportCrash(actorName string, event interface{}, reason interface{}, directive SupervisorDirective) {
 	system.lock.RLock()
-	defer system.lock.RUnlock()
-	return system.router.Route(actorName, system.actors)
+	reporter := system.crashReporter
+	system.lock.RUnlock()
+	reporter.Report(actorName, event, reason, directive)
+}
+
+// decide applies the actor's strategy to a crash. An escalated crash is
+// handed to the default strategy, escalating from there takes the whole
+// system down.
+func (system *ActorSystem) decide(actorName string, reason interface{}, window *restartWindow) SupervisorDirective {
+	system.lock.RLock()
+	strategy, ok := system.strategies[actorName]
+	defaultStrategy := system.defaultStrategy
+	system.lock.RUnlock()
+
+	if !ok {
+		strategy = defaultStrategy
+	}
+
+	directive := strategy.decide(actorName, reason)
+	if directive == DIRECTIVE_ESCALATE && strategy != defaultStrategy {
+		strategy = defaultStrategy
+		directive = strategy.decide(actorName, reason)
+	}
+
+	if directive == DIRECTIVE_RESTART && !window.allow(strategy) {
+		return DIRECTIVE_STOP
+	}
+	return directive
+}
+
+// route takes no system lock, routers work on the instances registered at
+// the moment
+func (system *ActorSystem) route(actorName string, event interface{}) (actor *innerActor, err error) {
+	switch router := system.router.(type) {
+	case RegistryRouter:
+		return router.RouteRegistry(actorName, event, system.registry)
+	case EventRouter:
+		return router.RouteEvent(actorName, event, system.registry.all())
+	}
+	return system.router.Route(actorName, system.registry.all())
+}
+
+// deliver sends event, chain holding the actors blocked until it is answered
+func (system *ActorSystem) deliver(ctx context.Context, actorName string, event interface{}, ch chan interface{}, timeout int, chain []*innerActor) (*Event, error) {
+	typedEvent := &Event{
+		event:        event,
+		responseChan: ch,
+		ctx:          ctx,
+		timeout:      time.Duration(timeout) * time.Millisecond,
+		chain:        chain,
+	}
+	return typedEvent, system.send(actorName, typedEvent)
+}
+
+func (system *ActorSystem) send(actorName string, event *Event) error {
+	if atomic.LoadInt32(&system.stopping) != 0 {
+		system.refuse(actorName, event, DEAD_LETTER_SHUTDOWN, ErrSystemShutdown)
+		return ErrSystemShutdown
+	}
+
+	if event.correlationID == 0 {
+		event.correlationID = atomic.AddUint64(&correlationSeq, 1)
+	}
+	if event.responseChan != nil && event.sentAt.IsZero() {
+		event.sentAt = time.Now()
+	}
+
+	if actor, err := system.route(actorName, event.event); err != nil {
+		system.refuse(actorName, event, DEAD_LETTER_NO_ROUTE, err)
+		return err
+	} else {
+		if err := system.wait(event, actor); err != nil {
+			return err
+		}
+		return actor.push(event)
+	}
 }
 
 func (system *ActorSystem) require(actorName string, event interface{}, timeout int) (rst interface{}, err error) {
-	if actor, err := system.route(actorName); err != nil {
-		system.deadLetterProcessor.Process(actorName, event)
+	var ch chan interface{}
+	if timeout == -1 {
+		ch = nil
+	} else {
+		ch = make(chan interface{}, 1)
+	}
+
+	typedEvent, err := system.deliver(nil, actorName, event, ch, timeout, system.chain(nil))
+	if err != nil {
 		return nil, err
+	}
+
+	if timeout >= 0 {
+		return system.awaitResponse(actorName, typedEvent, ch, timeout)
 	} else {
-		var ch chan interface{}
-		if timeout == -1 {
-			ch = nil
-		} else {
-			ch = make(chan interface{}, 1)
-		}
+		return nil, nil
+	}
+}
 
-		actor.push(&Event{
-			event:        event,
-			responseChan: ch,
-		})
+func (system *ActorSystem) awaitResponse(actorName string, event *Event, ch <-chan interface{}, timeout int) (rst interface{}, err error) {
+	timer := time.NewTimer(time.Duration(timeout) * time.Millisecond)
+	defer timer.Stop()
+	defer system.unwait(event)
 
-		if timeout >= 0 {
-			select {
-			case rst = <-ch:
-				return rst, nil
-			case <-time.After(time.Duration(timeout) * time.Millisecond):
-				return nil, errors.New(fmt.Sprintf("require to %s timeout", actorName))
-			}
-		} else {
-			return nil, nil
+	select {
+	case rst = <-ch:
+		return unwrapResponse(rst)
+	case <-timer.C:
+		if !system.abandon(event) {
+			// the response raced the timeout and is on its way
+			return unwrapResponse(<-ch)
+		}
+		err := errors.New(fmt.Sprintf("require to %s timeout", actorName) + waitsForText(system.waitsFor()))
+		system.logWaitsFor(err)
+		return nil, err
+	}
+}
+
+func (system *ActorSystem) awaitResponseCtx(ctx context.Context, event *Event, ch <-chan interface{}) (rst interface{}, err error) {
+	defer system.unwait(event)
+	select {
+	case rst = <-ch:
+		return unwrapResponse(rst)
+	case <-ctx.Done():
+		if !system.abandon(event) {
+			return unwrapResponse(<-ch)
 		}
+		return nil, ctx.Err()
+	}
+}
+
+func unwrapResponse(rst interface{}) (interface{}, error) {
+	if failed, ok := rst.(*failedResponse); ok {
+		return nil, failed.err
 	}
+	return rst, nil
 }
 
-func (system *ActorSystem) Request(actorName string, event interface{}) {
-	_, _ = system.require(actorName, event, -1)
+func (system *ActorSystem) Request(actorName string, event interface{}) error {
+	_, err := system.require(actorName, event, -1)
+	return err
+}
+
+// RequestAll sends event to one instance of every name matching pattern,
+// if the router is a MultiRouter. Otherwise it is just a Request.
+func (system *ActorSystem) RequestAll(pattern string, event interface{}) error {
+	if atomic.LoadInt32(&system.stopping) != 0 {
+		system.deadLetter(pattern, &Event{event: event}, DEAD_LETTER_SHUTDOWN, ErrSystemShutdown)
+		return ErrSystemShutdown
+	}
+
+	router, ok := system.router.(MultiRouter)
+	if !ok {
+		return system.Request(pattern, event)
+	}
+	matched, err := router.RouteAll(pattern, event, system.registry.all())
+
+	if err != nil {
+		system.deadLetter(pattern, &Event{event: event}, DEAD_LETTER_NO_ROUTE, err)
+		return err
+	}
+
+	correlationID := atomic.AddUint64(&correlationSeq, 1)
+	for _, actor := range matched {
+		if e := actor.push(&Event{
+			event:         event,
+			responseChan:  nil,
+			correlationID: correlationID,
+		}); e != nil {
+			err = e
+		}
+	}
+	return err
 }
 
 func (system *ActorSystem) Require(actorName string, event interface{}, timeoutInMilliSec int) (rst interface{}, err error) {
 	return system.require(actorName, event, timeoutInMilliSec)
 }
 
+func (system *ActorSystem) RequestCtx(ctx context.Context, actorName string, event interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := system.deliver(ctx, actorName, event, nil, 0, nil)
+	return err
+}
+
+func (system *ActorSystem) RequireCtx(ctx context.Context, actorName string, event interface{}) (rst interface{}, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan interface{}, 1)
+	typedEvent, err := system.deliver(ctx, actorName, event, ch, 0, system.chain(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return system.awaitResponseCtx(ctx, typedEvent, ch)
+}
+
+func (system *ActorSystem) RequireAsync(actorName string, event interface{}, timeoutInMilliSec int) *Future {
+	future := newFuture()
+	ch := make(chan interface{}, 1)
+	if typedEvent, err := system.deliver(nil, actorName, event, ch, timeoutInMilliSec, nil); err != nil {
+		future.complete(nil, err)
+	} else {
+		go func() {
+			future.complete(system.awaitResponse(actorName, typedEvent, ch, timeoutInMilliSec))
+		}()
+	}
+	return future
+}
+
+func (system *ActorSystem) RequireAsyncCtx(ctx context.Context, actorName string, event interface{}) *Future {
+	future := newFuture()
+	if err := ctx.Err(); err != nil {
+		future.complete(nil, err)
+		return future
+	}
+
+	ch := make(chan interface{}, 1)
+	if typedEvent, err := system.deliver(ctx, actorName, event, ch, 0, nil); err != nil {
+		future.complete(nil, err)
+	} else {
+		go func() {
+			future.complete(system.awaitResponseCtx(ctx, typedEvent, ch))
+		}()
+	}
+	return future
+}
+
+func (system *ActorSystem) instance(actorName string, actorImpl ActorInterface) *innerActor {
+	system.lock.RLock()
+	defer system.lock.RUnlock()
+	for _, actor := range system.registry.lookup(actorName) {
+		if actor.actorImpl == actorImpl {
+			return actor
+		}
+	}
+	return nil
+}
+
 func NewDefaultActorSystem() *ActorSystem {
-	return &ActorSystem{
-		router:              NewFullQualifiedNameWithRandomBalancerRouter(),
-		deadLetterProcessor: NewConsoleDeadLetterProcessor(),
-		actors:              make(map[string][]*innerActor),
+	return NewActorSystem(NewFullQualifiedNameWithRandomBalancerRouter(), NewConsoleDeadLetterProcessor())
+}
+
+func NewActorSystem(router Router, deadLetterProcessor DeadLetterProcessor) *ActorSystem {
+	system := &ActorSystem{systemState: &systemState{
+		router:              router,
+		deadLetterProcessor: deadLetterProcessor,
 		lock:                &sync.RWMutex{},
-	}
+		crashReporter:       NewConsoleCrashReporter(),
+		defaultStrategy:     NewRestartStrategy(10, time.Minute),
+		strategies:          make(map[string]*SupervisorStrategy),
+		alive:               make(map[string]int),
+		watchers:            make(map[string]map[string]bool),
+		draining:            make(map[string][]*innerActor),
+		schedules:           make(map[string]map[*Schedule]struct{}),
+		stream:              newEventStream(),
+		defaultDispatcher:   NewPinnedDispatcher(),
+		dispatchers:         make(map[string]Dispatcher),
+		waits:               newWaitsFor(),
+		remotes:             make(map[string]*remoteNode),
+		serializers:         DefaultSerializers,
+		registry:            newActorRegistry(),
+	}}
+	return system
 }
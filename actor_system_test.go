@@ -3,6 +3,7 @@ package goactor
 import (
 	"fmt"
 	"math/rand"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -142,3 +143,214 @@ func TestActorSystem(t *testing.T) {
 
 	fmt.Println("Actor system total call count: ", count)
 }
+
+func TestRemoveActor(t *testing.T) {
+	system := NewDefaultActorSystem()
+	first, second := new(mockActor), new(mockActor)
+	system.AddActor("removable", first)
+	system.AddActor("removable", second)
+	defer system.Shutdown()
+
+	if ok, err := system.RemoveActor("removable", first); !ok || err != nil {
+		t.Errorf("remove one of two failed: %v", err)
+	}
+	if ok, err := system.RemoveActor("removable", second); !ok || err != nil {
+		t.Errorf("remove the last one failed: %v", err)
+	}
+	if ok, err := system.RemoveActor("removable", second); ok || err == nil {
+		t.Error("expect removing twice to fail")
+	}
+	if _, err := system.route("removable", nil); err == nil {
+		t.Error("expect no route once removed")
+	}
+}
+
+func TestRouteWhileChanging(t *testing.T) {
+	system := NewActorSystem(NewFullQualifiedNameWithCustomBalancerRouter(NewRoundRobinBalancer()), &recordDeadLetterProcessor{make(chan interface{}, 10000)})
+	stable := new(mockActor)
+	system.AddActor("churn", stable)
+	defer system.Shutdown()
+
+	var wg sync.WaitGroup
+	var failed int32
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if actor, err := system.route("churn", nil); err != nil || actor == nil {
+					atomic.AddInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		actorImpl := new(mockActor)
+		system.AddActor("churn", actorImpl)
+		system.RemoveActor("churn", actorImpl)
+	}
+	close(stop)
+	wg.Wait()
+
+	if failed != 0 {
+		t.Errorf("%d routes failed while the registry changed", failed)
+	}
+}
+
+func newBenchmarkSystem(names int) *ActorSystem {
+	system := NewDefaultActorSystem()
+	for i := 0; i < names; i++ {
+		system.AddActor(fmt.Sprintf("bench%d", i), new(mockActor))
+	}
+	return system
+}
+
+func BenchmarkRouteParallel(b *testing.B) {
+	system := newBenchmarkSystem(16)
+	defer system.Shutdown()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			system.route("bench7", nil)
+		}
+	})
+}
+
+func BenchmarkRouteParallelWithChurn(b *testing.B) {
+	system := newBenchmarkSystem(16)
+	defer system.Shutdown()
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			actorImpl := new(mockActor)
+			system.AddActor("bench7", actorImpl)
+			system.RemoveActor("bench7", actorImpl)
+		}
+	}()
+	defer close(stop)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			system.route("bench7", nil)
+		}
+	})
+}
+
+// lockedRegistry routes the way the system did before the registry, under
+// the read lock of a map changed in place
+type lockedRegistry struct {
+	actors   map[string][]*innerActor
+	lock     sync.RWMutex
+	balancer Balancer
+}
+
+func newLockedRegistry(names int) *lockedRegistry {
+	registry := &lockedRegistry{actors: make(map[string][]*innerActor), balancer: NewRandomBalancer()}
+	for i := 0; i < names; i++ {
+		registry.add(fmt.Sprintf("bench%d", i))
+	}
+	return registry
+}
+
+func (registry *lockedRegistry) add(name string) *innerActor {
+	actor := &innerActor{name: name}
+	registry.lock.Lock()
+	registry.actors[name] = append(registry.actors[name], actor)
+	registry.lock.Unlock()
+	return actor
+}
+
+func (registry *lockedRegistry) remove(actor *innerActor) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	for i, actress := range registry.actors[actor.name] {
+		if actress == actor {
+			registry.actors[actor.name] = append(registry.actors[actor.name][:i], registry.actors[actor.name][i+1:]...)
+			return
+		}
+	}
+}
+
+func (registry *lockedRegistry) route(name string) *innerActor {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	if actors, ok := registry.actors[name]; ok && len(actors) > 0 {
+		return registry.balancer.Choose(name, actors)
+	}
+	return nil
+}
+
+func BenchmarkRouteParallelRWMutex(b *testing.B) {
+	registry := newLockedRegistry(16)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			registry.route("bench7")
+		}
+	})
+}
+
+func BenchmarkRouteParallelRWMutexWithChurn(b *testing.B) {
+	registry := newLockedRegistry(16)
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			registry.remove(registry.add("bench7"))
+		}
+	}()
+	defer close(stop)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			registry.route("bench7")
+		}
+	})
+}
+
+func BenchmarkRequireParallel(b *testing.B) {
+	system := newBenchmarkSystem(16)
+	defer system.Shutdown()
+
+	var seq uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		name := fmt.Sprintf("bench%d", atomic.AddUint64(&seq, 1)%16)
+		for pb.Next() {
+			system.Require(name, 1, 1000)
+		}
+	})
+}
+
+func BenchmarkAddActors(b *testing.B) {
+	system := NewDefaultActorSystem()
+	system.SetDefaultDispatcher(NewPoolDispatcher(0, 0))
+	defer system.Shutdown()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		system.AddActor(fmt.Sprintf("bench%d", i), new(mockActor))
+	}
+}
@@ -45,7 +45,7 @@ func TestActor(t *testing.T) {
 		t.Error("actor not plugged in")
 	}
 
-	actor.push(&Event{"test1", nil})
+	actor.push(&Event{event: "test1"})
 
 	// a moment for the actor process message
 	time.Sleep(time.Duration(10) * time.Millisecond)
@@ -55,7 +55,7 @@ func TestActor(t *testing.T) {
 	}
 
 	cn := make(chan interface{}, 1)
-	actor.push(&Event{"test2", cn})
+	actor.push(&Event{event: "test2", responseChan: cn})
 	if response := <-cn; response != "test2" {
 		t.Error("actor didn't respond correct message")
 	}
@@ -63,7 +63,7 @@ func TestActor(t *testing.T) {
 		t.Error("actor didn't get require message")
 	}
 
-	actor.push(&Event{ExitEvent(0), nil})
+	actor.push(&Event{event: ExitEvent(0)})
 
 	// a moment for the actor deconstruct
 	time.Sleep(time.Duration(10) * time.Millisecond)
@@ -2,25 +2,179 @@ package goactor
 
 import (
 	"math/rand"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
 type Balancer interface {
 	Choose(actorName string, actors []*innerActor) *innerActor
 }
 
+// forgetter is implemented by routers and balancers keeping state per actor
+// name, dropped once no instance is left under the name
+type forgetter interface {
+	forget(actorName string)
+}
+
+// RandomBalancer draws from the top-level math/rand functions, which are safe
+// for concurrent routing without a lock of its own
 type RandomBalancer struct {
-	rand *rand.Rand
 }
 
 func NewRandomBalancer() Balancer {
-	s1 := rand.NewSource(time.Now().UnixNano())
-	return &RandomBalancer{
-		rand: rand.New(s1),
-	}
+	return &RandomBalancer{}
+}
+
+func (balancer RandomBalancer) intn(n int) int {
+	return rand.Intn(n)
 }
 
 func (balancer RandomBalancer) Choose(actorName string, actors []*innerActor) *innerActor {
-	index := balancer.rand.Intn(len(actors))
+	index := balancer.intn(len(actors))
 	return actors[index]
 }
+
+type RoundRobinBalancer struct {
+	counters *sync.Map // actor name -> *uint64
+}
+
+func NewRoundRobinBalancer() Balancer {
+	return &RoundRobinBalancer{
+		counters: &sync.Map{},
+	}
+}
+
+func (balancer *RoundRobinBalancer) Choose(actorName string, actors []*innerActor) *innerActor {
+	counter, ok := balancer.counters.Load(actorName)
+	if !ok {
+		counter, _ = balancer.counters.LoadOrStore(actorName, new(uint64))
+	}
+	index := (atomic.AddUint64(counter.(*uint64), 1) - 1) % uint64(len(actors))
+	return actors[index]
+}
+
+func (balancer *RoundRobinBalancer) forget(actorName string) {
+	balancer.counters.Delete(actorName)
+}
+
+// LeastLoadedBalancer picks the instance with the fewest queued events
+type LeastLoadedBalancer struct {
+}
+
+func NewLeastLoadedBalancer() Balancer {
+	return &LeastLoadedBalancer{}
+}
+
+func (balancer *LeastLoadedBalancer) Choose(actorName string, actors []*innerActor) *innerActor {
+	chosen := actors[0]
+	least := chosen.load()
+	for _, actor := range actors[1:] {
+		if load := actor.load(); load < least {
+			chosen, least = actor, load
+		}
+	}
+	return chosen
+}
+
+// PowerOfTwoChoicesBalancer picks the less loaded one of two random instances
+type PowerOfTwoChoicesBalancer struct {
+	random RandomBalancer
+}
+
+func NewPowerOfTwoChoicesBalancer() Balancer {
+	return &PowerOfTwoChoicesBalancer{
+		random: *NewRandomBalancer().(*RandomBalancer),
+	}
+}
+
+func (balancer *PowerOfTwoChoicesBalancer) Choose(actorName string, actors []*innerActor) *innerActor {
+	if len(actors) == 1 {
+		return actors[0]
+	}
+
+	i := balancer.random.intn(len(actors))
+	j := balancer.random.intn(len(actors) - 1)
+	if j >= i {
+		j++
+	}
+	if actors[j].load() < actors[i].load() {
+		return actors[j]
+	}
+	return actors[i]
+}
+
+// WeightedBalancer picks instances randomly in proportion to the weight
+// given by WithWeight on AddActor
+type WeightedBalancer struct {
+	random RandomBalancer
+}
+
+func NewWeightedBalancer() Balancer {
+	return &WeightedBalancer{
+		random: *NewRandomBalancer().(*RandomBalancer),
+	}
+}
+
+func (balancer *WeightedBalancer) Choose(actorName string, actors []*innerActor) *innerActor {
+	total := 0
+	for _, actor := range actors {
+		total += actor.balanceWeight()
+	}
+
+	point := balancer.random.intn(total)
+	for _, actor := range actors {
+		if point -= actor.balanceWeight(); point < 0 {
+			return actor
+		}
+	}
+	return actors[len(actors)-1]
+}
+
+func WithWeight(weight int) ActorOption {
+	return func(actor *innerActor) {
+		actor.weight = weight
+	}
+}
+
+func (actor *innerActor) balanceWeight() int {
+	if actor.weight <= 0 {
+		return 1
+	}
+	return actor.weight
+}
+
+func (actor *innerActor) load() int32 {
+	return atomic.LoadInt32(&actor.size)
+}
+
+// PerNameBalancer lets every actor name have its own balancer
+type PerNameBalancer struct {
+	defaultBalancer Balancer
+	balancers       map[string]Balancer
+}
+
+func NewPerNameBalancer(defaultBalancer Balancer, balancers map[string]Balancer) Balancer {
+	return &PerNameBalancer{
+		defaultBalancer: defaultBalancer,
+		balancers:       balancers,
+	}
+}
+
+func (balancer *PerNameBalancer) of(actorName string) Balancer {
+	if b, ok := balancer.balancers[actorName]; ok {
+		return b
+	}
+	return balancer.defaultBalancer
+}
+
+func (balancer *PerNameBalancer) Choose(actorName string, actors []*innerActor) *innerActor {
+	return balancer.of(actorName).Choose(actorName, actors)
+}
+
+func (balancer *PerNameBalancer) ChooseEvent(actorName string, event interface{}, actors []*innerActor) *innerActor {
+	return choose(balancer.of(actorName), actorName, event, actors)
+}
+
+func (balancer *PerNameBalancer) forget(actorName string) {
+	forget(balancer.of(actorName), actorName)
+}
@@ -1,6 +1,9 @@
 package goactor
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 type mockActor string
 
@@ -45,3 +48,162 @@ func TestBalancer(t *testing.T) {
 		}
 	}
 }
+
+func newBalancedActors(names ...string) []*innerActor {
+	actors := make([]*innerActor, len(names))
+	for i, name := range names {
+		actors[i] = &innerActor{name: name}
+	}
+	return actors
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	actors := newBalancedActors("A", "B", "C")
+	balancer := NewRoundRobinBalancer()
+
+	for i := 0; i < 9; i++ {
+		if actor := balancer.Choose("na", actors); actor != actors[i%3] {
+			t.Errorf("round %d: expect %s, got %s", i, actors[i%3].name, actor.name)
+		}
+	}
+
+	if actor := balancer.Choose("other", actors); actor != actors[0] {
+		t.Error("round robin should count per actor name")
+	}
+}
+
+func TestLeastLoadedBalancer(t *testing.T) {
+	actors := newBalancedActors("A", "B", "C")
+	actors[0].size, actors[1].size, actors[2].size = 5, 1, 3
+
+	if actor := NewLeastLoadedBalancer().Choose("na", actors); actor.name != "B" {
+		t.Errorf("expect B, got %s", actor.name)
+	}
+}
+
+func TestPowerOfTwoChoicesBalancer(t *testing.T) {
+	actors := newBalancedActors("A", "B", "C")
+	actors[0].size, actors[1].size, actors[2].size = 100, 1, 2
+	balancer := NewPowerOfTwoChoicesBalancer()
+
+	for i := 0; i < 1000; i++ {
+		if actor := balancer.Choose("na", actors); actor.name == "A" {
+			t.Fatal("most loaded actor chosen")
+		}
+	}
+}
+
+func TestWeightedBalancer(t *testing.T) {
+	actors := newBalancedActors("A", "B")
+	WithWeight(3)(actors[0])
+	balancer := NewWeightedBalancer()
+
+	count := 0
+	for i := 0; i < 10000; i++ {
+		if balancer.Choose("na", actors).name == "A" {
+			count++
+		}
+	}
+	if count <= 7500-300 || count >= 7500+300 {
+		t.Errorf("WeightedBalancer doesn't follow weights, A chosen %d times", count)
+	}
+}
+
+func TestCustomBalancerSystem(t *testing.T) {
+	system := NewActorSystem(NewFullQualifiedNameWithCustomBalancerRouter(NewRoundRobinBalancer()), NewConsoleDeadLetterProcessor())
+	first := &slowActor{0, new(int32), new(int32)}
+	second := &slowActor{0, new(int32), new(int32)}
+	system.AddActor("balanced", first, WithWeight(2))
+	system.AddActor("balanced", second)
+	defer system.Shutdown()
+
+	for i := 0; i < 10; i++ {
+		system.Require("balanced", i, 1000)
+	}
+	if *first.handled != 5 || *second.handled != 5 {
+		t.Errorf("expect 5 events each, got %d and %d", *first.handled, *second.handled)
+	}
+}
+
+type userEvent string
+
+func (event userEvent) HashKey() string {
+	return string(event)
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	actors := newBalancedActors("A", "B", "C", "D")
+	for i, actor := range actors {
+		actor.id = uint64(i + 1)
+	}
+	balancer := NewConsistentHashBalancer(100, nil).(EventBalancer)
+
+	keys := make([]userEvent, 1000)
+	owners := make(map[userEvent]string)
+	count := make(map[string]int)
+	for i := range keys {
+		keys[i] = userEvent(fmt.Sprintf("user-%d", i))
+		owners[keys[i]] = balancer.ChooseEvent("na", keys[i], actors).name
+		count[owners[keys[i]]]++
+	}
+
+	for _, actor := range actors {
+		if count[actor.name] < 100 {
+			t.Errorf("%s owns too few keys: %d", actor.name, count[actor.name])
+		}
+	}
+
+	for _, key := range keys {
+		if owner := balancer.ChooseEvent("na", key, actors).name; owner != owners[key] {
+			t.Fatalf("key %s moved from %s to %s", key, owners[key], owner)
+		}
+	}
+
+	// removing C only remaps keys owned by C
+	removed := []*innerActor{actors[0], actors[1], actors[3]}
+	for _, key := range keys {
+		owner := balancer.ChooseEvent("na", key, removed).name
+		if owners[key] != "C" && owner != owners[key] {
+			t.Fatalf("key %s moved from %s to %s after removing C", key, owners[key], owner)
+		}
+	}
+
+	// adding E only remaps keys to E
+	added := append(newBalancedActors("E"), actors...)
+	added[0].id = 5
+	for _, key := range keys {
+		owner := balancer.ChooseEvent("na", key, added).name
+		if owner != "E" && owner != owners[key] {
+			t.Fatalf("key %s moved from %s to %s after adding E", key, owners[key], owner)
+		}
+	}
+
+	keyOf := func(event interface{}) (string, bool) {
+		return fmt.Sprint(event), true
+	}
+	byFunc := NewConsistentHashBalancer(10, keyOf).(EventBalancer)
+	if byFunc.ChooseEvent("na", 42, actors) != byFunc.ChooseEvent("na", 42, actors) {
+		t.Error("key function not used")
+	}
+}
+
+func TestConsistentHashBalancerForgets(t *testing.T) {
+	balancer := NewConsistentHashBalancer(10, nil).(*ConsistentHashBalancer)
+	system := NewActorSystem(NewFullQualifiedNameWithCustomBalancerRouter(balancer), &recordDeadLetterProcessor{make(chan interface{}, 10)})
+	defer system.Shutdown()
+
+	actor := newGateActor()
+	close(actor.gate)
+	system.AddActor("hashed", actor)
+	if _, err := system.Require("hashed", userEvent("user-1"), 1000); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := balancer.rings.Load("hashed"); !ok {
+		t.Fatal("ring not built")
+	}
+
+	system.RemoveActor("hashed", actor)
+	if _, ok := balancer.rings.Load("hashed"); ok {
+		t.Error("ring kept for a removed name")
+	}
+}
@@ -0,0 +1,53 @@
+package goactor
+
+// Behavior takes over the messages of an actor after Become, until
+// Unbecome. Like ReceiveMessage, nothing is answered automatically.
+type Behavior func(msg *Message)
+
+// Become stacks behavior on top of the receive function of the actor,
+// it handles the messages from the next one on
+func (msg *Message) Become(behavior Behavior) {
+	msg.actor.behaviors = append(msg.actor.behaviors, behavior)
+}
+
+// Unbecome restores the behavior before the last Become
+func (msg *Message) Unbecome() {
+	if behaviors := msg.actor.behaviors; len(behaviors) > 0 {
+		behaviors[len(behaviors)-1] = nil
+		msg.actor.behaviors = behaviors[:len(behaviors)-1]
+	}
+}
+
+// Stash puts the message aside until UnstashAll. A require stays pending,
+// its caller gets whatever is replied once the message is unstashed.
+func (msg *Message) Stash() {
+	msg.actor.stash.push(msg.event)
+}
+
+// UnstashAll hands the stashed messages back to the actor in their order,
+// ahead of its mailbox
+func (msg *Message) UnstashAll() int {
+	return msg.actor.unstashAll()
+}
+
+func (actor *innerActor) behavior() Behavior {
+	if len(actor.behaviors) == 0 {
+		return nil
+	}
+	return actor.behaviors[len(actor.behaviors)-1]
+}
+
+func (actor *innerActor) unstashAll() int {
+	events := actor.stash.take()
+	for _, event := range events {
+		actor.unstashed.push(event)
+	}
+	return len(events)
+}
+
+// reset forgets behaviors, before the implementation restarts or gets
+// replaced
+func (actor *innerActor) reset() {
+	actor.behaviors = nil
+	actor.unstashAll()
+}
@@ -0,0 +1,59 @@
+package goactor
+
+import (
+	"fmt"
+	"testing"
+)
+
+type connectionActor struct{}
+
+func (actor *connectionActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *connectionActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *connectionActor) OnPullout(system *ActorSystem) {}
+
+func (actor *connectionActor) ReceiveMessage(msg *Message) {
+	if msg.Event != "handshake" {
+		msg.Stash()
+		return
+	}
+
+	msg.Become(func(msg *Message) {
+		if msg.Event == "close" {
+			msg.Unbecome()
+		}
+		msg.Reply(fmt.Sprintf("served %v", msg.Event))
+	})
+	msg.UnstashAll()
+	msg.Reply("ready")
+}
+
+func TestBecomeAndStash(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("connection", &connectionActor{})
+	defer system.Shutdown()
+
+	early := []*Future{
+		system.RequireAsync("connection", "first", 1000),
+		system.RequireAsync("connection", "second", 1000),
+	}
+
+	if rst, err := system.Require("connection", "handshake", 1000); err != nil || rst != "ready" {
+		t.Fatalf("unexpected handshake response %v, %v", rst, err)
+	}
+	for i, expect := range []string{"served first", "served second"} {
+		if rst, err := early[i].Await(); err != nil || rst != expect {
+			t.Errorf("expect %s, got %v, %v", expect, rst, err)
+		}
+	}
+
+	if rst, err := system.Require("connection", "close", 1000); err != nil || rst != "served close" {
+		t.Errorf("unexpected close response %v, %v", rst, err)
+	}
+	if _, err := system.Require("connection", "after close", 50); err == nil {
+		t.Error("expect the event stashed again after unbecome")
+	}
+}
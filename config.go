@@ -0,0 +1,312 @@
+package goactor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+type ActorFactory func(config map[string]interface{}) ActorInterface
+
+type MailboxConfig struct {
+	Capacity int    `json:"capacity"`
+	Overflow string `json:"overflow"`
+}
+
+type ActorConfig struct {
+	Name      string                 `json:"name"`
+	Factory   string                 `json:"factory"`
+	Instances int                    `json:"instances"`
+	Balancer  string                 `json:"balancer"`
+	Weight    int                    `json:"weight"`
+	Mailbox   *MailboxConfig         `json:"mailbox"`
+	Config    map[string]interface{} `json:"config"`
+	Disabled  bool                   `json:"disabled"`
+}
+
+// SystemConfig is what a config file turns into after the selected profile
+// is merged in. Router is one of "name" (default), "path" or "type".
+type SystemConfig struct {
+	Router   string         `json:"router"`
+	Balancer string         `json:"balancer"`
+	Actors   []*ActorConfig `json:"actors"`
+}
+
+var (
+	factories     = make(map[string]ActorFactory)
+	configFormats = map[string]func(data []byte, v interface{}) error{
+		".json": json.Unmarshal,
+		".yaml": yaml.Unmarshal,
+		".yml":  yaml.Unmarshal,
+	}
+	configLock = &sync.RWMutex{}
+)
+
+func RegisterFactory(name string, factory ActorFactory) {
+	configLock.Lock()
+	defer configLock.Unlock()
+	factories[name] = factory
+}
+
+// RegisterConfigFormat teaches LoadActorSystem another file extension, e.g.
+// RegisterConfigFormat(".toml", toml.Unmarshal); .json, .yaml and .yml are
+// known already. The unmarshal func must be able to decode into
+// map[string]interface{}, nested maps may have keys of any type as yaml.v2
+// gives.
+func RegisterConfigFormat(extension string, unmarshal func(data []byte, v interface{}) error) {
+	configLock.Lock()
+	defer configLock.Unlock()
+	configFormats[strings.ToLower(extension)] = unmarshal
+}
+
+// LoadActorSystem builds an ActorSystem from a config file. An empty profile
+// falls back to $GOACTOR_PROFILE, then to the "profile" key of the file.
+func LoadActorSystem(path string, profile string) (*ActorSystem, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	configLock.RLock()
+	unmarshal, ok := configFormats[strings.ToLower(filepath.Ext(path))]
+	configLock.RUnlock()
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("no config format registered for \"%s\"", filepath.Ext(path)))
+	}
+
+	config, err := ParseSystemConfig(data, unmarshal, profile)
+	if err != nil {
+		return nil, err
+	}
+	return NewActorSystemFromConfig(config)
+}
+
+// ParseSystemConfig decodes a config and merges the selected profile of its
+// "profiles" section over it. Actors are merged by name, key by key.
+func ParseSystemConfig(data []byte, unmarshal func(data []byte, v interface{}) error, profile string) (*SystemConfig, error) {
+	var raw map[string]interface{}
+	if err := unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	raw = stringKeys(raw).(map[string]interface{})
+
+	if profile == "" {
+		profile = os.Getenv("GOACTOR_PROFILE")
+	}
+	if profile == "" {
+		profile, _ = raw["profile"].(string)
+	}
+
+	if profile != "" {
+		profiles, _ := raw["profiles"].(map[string]interface{})
+		override, ok := profiles[profile].(map[string]interface{})
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("profile \"%s\" not found in config", profile))
+		}
+		raw = mergeConfig(raw, override)
+	}
+	delete(raw, "profile")
+	delete(raw, "profiles")
+
+	// normalize whatever the format produced through json
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	config := &SystemConfig{}
+	if err := json.Unmarshal(normalized, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func mergeConfig(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+
+	for k, v := range override {
+		if k == "actors" {
+			merged[k] = mergeActors(base[k], v)
+			continue
+		}
+		baseMap, baseOk := base[k].(map[string]interface{})
+		overrideMap, overrideOk := v.(map[string]interface{})
+		if baseOk && overrideOk {
+			merged[k] = mergeConfig(baseMap, overrideMap)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func mergeActors(base interface{}, override interface{}) interface{} {
+	baseActors, _ := base.([]interface{})
+	overrideActors, ok := override.([]interface{})
+	if !ok {
+		return override
+	}
+
+	merged := make([]interface{}, len(baseActors))
+	copy(merged, baseActors)
+	for _, o := range overrideActors {
+		overrideActor, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		found := false
+		for i, b := range merged {
+			if baseActor, ok := b.(map[string]interface{}); ok && baseActor["name"] == overrideActor["name"] {
+				merged[i] = mergeConfig(baseActor, overrideActor)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, overrideActor)
+		}
+	}
+	return merged
+}
+
+func NewActorSystemFromConfig(config *SystemConfig) (*ActorSystem, error) {
+	defaultBalancer, err := newBalancerByName(config.Balancer)
+	if err != nil {
+		return nil, err
+	}
+
+	balancers := make(map[string]Balancer)
+	for _, actorConfig := range config.Actors {
+		if actorConfig.Balancer != "" {
+			if balancers[actorConfig.Name], err = newBalancerByName(actorConfig.Balancer); err != nil {
+				return nil, err
+			}
+		}
+	}
+	balancer := NewPerNameBalancer(defaultBalancer, balancers)
+
+	var router Router
+	switch config.Router {
+	case "", "name":
+		router = NewFullQualifiedNameWithCustomBalancerRouter(balancer)
+	case "path":
+		router = NewPathRouter(balancer)
+	case "type":
+		router = NewTypeRouter(NewFullQualifiedNameWithCustomBalancerRouter(balancer), balancer)
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown router \"%s\"", config.Router))
+	}
+
+	type plan struct {
+		config  *ActorConfig
+		factory ActorFactory
+		options []ActorOption
+	}
+	plans := make([]*plan, 0, len(config.Actors))
+	for _, actorConfig := range config.Actors {
+		if actorConfig.Disabled {
+			continue
+		}
+
+		configLock.RLock()
+		factory, ok := factories[actorConfig.Factory]
+		configLock.RUnlock()
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("no factory \"%s\" registered for actor \"%s\"", actorConfig.Factory, actorConfig.Name))
+		}
+
+		options := []ActorOption{}
+		if actorConfig.Weight > 0 {
+			options = append(options, WithWeight(actorConfig.Weight))
+		}
+		if actorConfig.Mailbox != nil {
+			overflow, err := overflowPolicyByName(actorConfig.Mailbox.Overflow)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, WithMailbox(actorConfig.Mailbox.Capacity, overflow))
+		}
+		plans = append(plans, &plan{actorConfig, factory, options})
+	}
+
+	system := NewActorSystem(router, NewConsoleDeadLetterProcessor())
+	for _, p := range plans {
+		instances := p.config.Instances
+		if instances <= 0 {
+			instances = 1
+		}
+		for i := 0; i < instances; i++ {
+			if _, err := system.AddActor(p.config.Name, p.factory(p.config.Config), p.options...); err != nil {
+				system.Shutdown()
+				return nil, err
+			}
+		}
+	}
+	return system, nil
+}
+
+func newBalancerByName(name string) (Balancer, error) {
+	switch name {
+	case "", "random":
+		return NewRandomBalancer(), nil
+	case "round_robin":
+		return NewRoundRobinBalancer(), nil
+	case "least_loaded":
+		return NewLeastLoadedBalancer(), nil
+	case "power_of_two":
+		return NewPowerOfTwoChoicesBalancer(), nil
+	case "weighted":
+		return NewWeightedBalancer(), nil
+	case "consistent_hash":
+		return NewConsistentHashBalancer(0, nil), nil
+	}
+	return nil, errors.New(fmt.Sprintf("unknown balancer \"%s\"", name))
+}
+
+func overflowPolicyByName(name string) (OverflowPolicy, error) {
+	switch name {
+	case "", "block":
+		return OVERFLOW_BLOCK, nil
+	case "drop_newest":
+		return OVERFLOW_DROP_NEWEST, nil
+	case "drop_oldest":
+		return OVERFLOW_DROP_OLDEST, nil
+	case "dead_letter":
+		return OVERFLOW_DEAD_LETTER, nil
+	}
+	return 0, errors.New(fmt.Sprintf("unknown overflow policy \"%s\"", name))
+}
+
+// stringKeys turns the map[interface{}]interface{} of formats like yaml.v2
+// into map[string]interface{}, which json and profiles merging expect
+func stringKeys(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			converted[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return converted
+	case map[string]interface{}:
+		for k, v := range typed {
+			typed[k] = stringKeys(v)
+		}
+		return typed
+	case []interface{}:
+		for i, v := range typed {
+			typed[i] = stringKeys(v)
+		}
+		return typed
+	}
+	return value
+}
@@ -0,0 +1,179 @@
+package goactor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+type taggedActor struct {
+	tag string
+}
+
+func (actor *taggedActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *taggedActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return actor.tag
+}
+
+func (actor *taggedActor) OnPullout(system *ActorSystem) {}
+
+const testConfig = `{
+	"profile": "dev",
+	"actors": [
+		{"name": "storage", "factory": "memory", "instances": 2, "balancer": "round_robin"},
+		{"name": "mailer", "factory": "memory", "config": {"tag": "mailer"}, "mailbox": {"capacity": 10, "overflow": "drop_newest"}}
+	],
+	"profiles": {
+		"dev": {},
+		"prd": {
+			"actors": [
+				{"name": "storage", "factory": "tagged", "config": {"tag": "database"}},
+				{"name": "mailer", "disabled": true}
+			]
+		}
+	}
+}`
+
+func init() {
+	RegisterFactory("memory", func(config map[string]interface{}) ActorInterface {
+		if tag, ok := config["tag"].(string); ok {
+			return &taggedActor{tag}
+		}
+		return &taggedActor{"memory"}
+	})
+	RegisterFactory("tagged", func(config map[string]interface{}) ActorInterface {
+		return &taggedActor{config["tag"].(string)}
+	})
+}
+
+func TestConfigProfiles(t *testing.T) {
+	dev, err := ParseSystemConfig([]byte(testConfig), json.Unmarshal, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.Actors) != 2 || dev.Actors[0].Factory != "memory" || dev.Actors[1].Mailbox.Capacity != 10 {
+		t.Errorf("unexpected dev config %+v", dev.Actors)
+	}
+
+	prd, err := ParseSystemConfig([]byte(testConfig), json.Unmarshal, "prd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := prd.Actors[0]
+	if storage.Factory != "tagged" || storage.Instances != 2 || storage.Balancer != "round_robin" || storage.Config["tag"] != "database" {
+		t.Errorf("profile not merged into storage: %+v", storage)
+	}
+	if !prd.Actors[1].Disabled {
+		t.Error("mailer not disabled in prd")
+	}
+
+	if _, err := ParseSystemConfig([]byte(testConfig), json.Unmarshal, "stg"); err == nil {
+		t.Error("unknown profile accepted")
+	}
+}
+
+const testYamlConfig = `
+profile: dev
+actors:
+  - name: storage
+    factory: memory
+    instances: 2
+    balancer: round_robin
+  - name: mailer
+    factory: memory
+    config:
+      tag: mailer
+    mailbox:
+      capacity: 10
+      overflow: drop_newest
+profiles:
+  dev: {}
+  prd:
+    actors:
+      - name: storage
+        factory: tagged
+        config:
+          tag: database
+      - name: mailer
+        disabled: true
+`
+
+func TestConfigYaml(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goactor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "actors.yml")
+	ioutil.WriteFile(path, []byte(testYamlConfig), 0644)
+
+	prd, err := LoadActorSystem(path, "prd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prd.Shutdown()
+	if rst, err := prd.Require("storage", nil, 1000); rst != "database" || err != nil {
+		t.Errorf("expect database storage in prd, got %v, %v", rst, err)
+	}
+	if _, err := prd.Require("mailer", nil, 100); err == nil {
+		t.Error("disabled mailer plugged in prd")
+	}
+
+	dev, err := ParseSystemConfig([]byte(testYamlConfig), yaml.Unmarshal, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.Actors) != 2 || dev.Actors[0].Instances != 2 || dev.Actors[1].Config["tag"] != "mailer" || dev.Actors[1].Mailbox.Capacity != 10 {
+		t.Errorf("unexpected dev config %+v", dev.Actors)
+	}
+}
+
+func TestLoadActorSystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goactor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "actors.json")
+	ioutil.WriteFile(path, []byte(testConfig), 0644)
+
+	dev, err := LoadActorSystem(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Shutdown()
+	if rst, err := dev.Require("storage", nil, 1000); rst != "memory" || err != nil {
+		t.Errorf("expect memory storage in dev, got %v, %v", rst, err)
+	}
+	if rst, err := dev.Require("mailer", nil, 1000); rst != "mailer" || err != nil {
+		t.Errorf("expect mailer in dev, got %v, %v", rst, err)
+	}
+
+	prd, err := LoadActorSystem(path, "prd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prd.Shutdown()
+	if rst, err := prd.Require("storage", nil, 1000); rst != "database" || err != nil {
+		t.Errorf("expect database storage in prd, got %v, %v", rst, err)
+	}
+	if _, err := prd.Require("mailer", nil, 100); err == nil {
+		t.Error("disabled mailer plugged in prd")
+	}
+
+	if _, err := LoadActorSystem(filepath.Join(dir, "actors.toml"), ""); err == nil {
+		t.Error("unregistered format accepted")
+	}
+}
+
+func TestConfigUnknownFactory(t *testing.T) {
+	config := &SystemConfig{Actors: []*ActorConfig{{Name: "x", Factory: "missing"}}}
+	if _, err := NewActorSystemFromConfig(config); err == nil {
+		t.Error("unknown factory accepted")
+	}
+}
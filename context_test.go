@@ -0,0 +1,84 @@
+package goactor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type contextKey string
+
+type contextActor struct {
+	gate chan struct{}
+}
+
+func (actor *contextActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *contextActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *contextActor) ReceiveContext(ctx context.Context, system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	if event == "wait" {
+		<-actor.gate
+	}
+	return ctx.Value(contextKey("user"))
+}
+
+func (actor *contextActor) OnPullout(system *ActorSystem) {}
+
+type reasonDeadLetterProcessor struct {
+	reasons chan error
+}
+
+func (processor *reasonDeadLetterProcessor) Process(actorName string, event interface{}) {}
+
+func (processor *reasonDeadLetterProcessor) ProcessWithReason(actorName string, event interface{}, reason error) {
+	processor.reasons <- reason
+}
+
+func TestRequireCtxValue(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("ctx", &contextActor{make(chan struct{})})
+	defer system.Shutdown()
+
+	ctx := context.WithValue(context.Background(), contextKey("user"), "alice")
+	if rst, err := system.RequireCtx(ctx, "ctx", "hello"); rst != "alice" || err != nil {
+		t.Errorf("context value not passed to actor, got %v, %v", rst, err)
+	}
+}
+
+func TestRequireCtxCancel(t *testing.T) {
+	system := NewDefaultActorSystem()
+	processor := &reasonDeadLetterProcessor{make(chan error, 10)}
+	system.deadLetterProcessor = processor
+	actor := &contextActor{make(chan struct{})}
+	system.AddActor("ctx", actor)
+	defer system.Shutdown()
+
+	// keep the actor busy, so the next require stays in mailbox
+	go system.Require("ctx", "wait", 1000)
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(10)*time.Millisecond)
+	defer cancel()
+	if _, err := system.RequireCtx(ctx, "ctx", "hello"); err != context.DeadlineExceeded {
+		t.Errorf("expect deadline exceeded, got %v", err)
+	}
+
+	actor.gate <- struct{}{}
+	select {
+	case reason := <-processor.reasons:
+		if reason != context.DeadlineExceeded {
+			t.Errorf("expect deadline exceeded as dead letter reason, got %v", reason)
+		}
+	case <-time.After(time.Second):
+		t.Error("abandoned event not reported as dead letter")
+	}
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if err := system.RequestCtx(cancelled, "ctx", "hello"); err != context.Canceled {
+		t.Errorf("expect canceled, got %v", err)
+	}
+}
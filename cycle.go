@@ -0,0 +1,148 @@
+package goactor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// RequireCycleError fails a require which would wait on an actor already
+// waiting for the caller, directly or through others. WaitsFor is the graph
+// of actors waiting for each other at the moment, if SetWaitsForDebug.
+type RequireCycleError struct {
+	Cycle    []string
+	WaitsFor []string
+}
+
+func (err *RequireCycleError) Error() string {
+	return fmt.Sprintf("require cycle detected: %s", strings.Join(err.Cycle, " -> ")) + waitsForText(err.WaitsFor)
+}
+
+func waitsForText(edges []string) string {
+	if len(edges) == 0 {
+		return ""
+	}
+	return ", waits-for graph: " + strings.Join(edges, ", ")
+}
+
+// chain is the one a blocking require made with ctx carries: the chain of
+// ctx if handed to an actor, or else the one of the actor this handle stands
+// for while it processes an event
+func (system *ActorSystem) chain(ctx context.Context) []*innerActor {
+	if callers := callersOf(ctx); callers != nil {
+		return callers
+	}
+	if actor := system.caller; actor != nil {
+		if event := actor.processing(); event != nil {
+			return actor.callers(event)
+		}
+	}
+	return nil
+}
+
+type callersKey struct{}
+
+func withCallers(ctx context.Context, callers []*innerActor) context.Context {
+	return context.WithValue(ctx, callersKey{}, callers)
+}
+
+func callersOf(ctx context.Context) []*innerActor {
+	if ctx == nil {
+		return nil
+	}
+	callers, _ := ctx.Value(callersKey{}).([]*innerActor)
+	return callers
+}
+
+// callers is the chain of a require made while processing event, which
+// only extends the chain of event if its own caller is waiting
+func (actor *innerActor) callers(event *Event) []*innerActor {
+	var chain []*innerActor
+	if event != nil && event.responseChan != nil {
+		chain = event.chain
+	}
+	return append(chain[:len(chain):len(chain)], actor)
+}
+
+type waitsFor struct {
+	edges map[*Event][2]string
+	lock  sync.Mutex
+}
+
+func newWaitsFor() *waitsFor {
+	return &waitsFor{edges: make(map[*Event][2]string)}
+}
+
+// SetWaitsForDebug logs the graph of actors waiting for each other on a
+// require cycle or a require timeout, and adds it to their error too
+func (system *ActorSystem) SetWaitsForDebug(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&system.debugWaits, 1)
+	} else {
+		atomic.StoreInt32(&system.debugWaits, 0)
+	}
+}
+
+// wait checks that event, a require, doesn't make target wait for itself
+func (system *ActorSystem) wait(event *Event, target *innerActor) error {
+	if event.responseChan == nil || len(event.chain) == 0 {
+		return nil
+	}
+
+	for i, caller := range event.chain {
+		if caller != target {
+			continue
+		}
+		cycle := make([]string, 0, len(event.chain)-i+1)
+		for _, waiting := range event.chain[i:] {
+			cycle = append(cycle, waiting.name)
+		}
+		err := &RequireCycleError{append(cycle, target.name), system.waitsFor()}
+		system.logWaitsFor(err)
+		return err
+	}
+
+	if atomic.LoadInt32(&system.debugWaits) != 0 {
+		system.waits.lock.Lock()
+		system.waits.edges[event] = [2]string{event.chain[len(event.chain)-1].name, target.name}
+		system.waits.lock.Unlock()
+	}
+	return nil
+}
+
+func (system *ActorSystem) unwait(event *Event) {
+	if len(event.chain) == 0 || atomic.LoadInt32(&system.debugWaits) == 0 {
+		return
+	}
+	system.waits.lock.Lock()
+	delete(system.waits.edges, event)
+	system.waits.lock.Unlock()
+}
+
+// waitsFor lists the edges of the waits-for graph, if debugging them
+func (system *ActorSystem) waitsFor() []string {
+	if atomic.LoadInt32(&system.debugWaits) == 0 {
+		return nil
+	}
+
+	system.waits.lock.Lock()
+	edges := make([]string, 0, len(system.waits.edges))
+	for _, edge := range system.waits.edges {
+		edges = append(edges, fmt.Sprintf("%s -> %s", edge[0], edge[1]))
+	}
+	system.waits.lock.Unlock()
+
+	sort.Strings(edges)
+	return edges
+}
+
+// logWaitsFor logs err, which carries the waits-for graph, if debugging it
+func (system *ActorSystem) logWaitsFor(err error) {
+	if atomic.LoadInt32(&system.debugWaits) != 0 {
+		log.Printf("%v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package goactor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type relayActor struct {
+	next string
+}
+
+func (actor *relayActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *relayActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *relayActor) OnPullout(system *ActorSystem) {}
+
+func (actor *relayActor) ReceiveMessage(msg *Message) {
+	if actor.next == "" {
+		msg.Reply("done")
+		return
+	}
+	rst, err := msg.Require(actor.next, msg.Event, 1000)
+	if err != nil {
+		msg.Reply(err)
+		return
+	}
+	msg.Reply(rst)
+}
+
+type contextRelayActor struct {
+	next string
+}
+
+func (actor *contextRelayActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *contextRelayActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *contextRelayActor) ReceiveContext(ctx context.Context, system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	rst, err := system.RequireCtx(ctx, actor.next, event)
+	if err != nil {
+		return err
+	}
+	return rst
+}
+
+func (actor *contextRelayActor) OnPullout(system *ActorSystem) {}
+
+type plainRelayActor struct {
+	next string
+}
+
+func (actor *plainRelayActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *plainRelayActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	rst, err := system.Require(actor.next, event, 300)
+	if err != nil {
+		return err
+	}
+	return rst
+}
+
+func (actor *plainRelayActor) OnPullout(system *ActorSystem) {}
+
+func TestRequireCycle(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.SetWaitsForDebug(true)
+	system.AddActor("a", &relayActor{"b"})
+	system.AddActor("b", &relayActor{"c"})
+	system.AddActor("c", &relayActor{"a"})
+	defer system.Shutdown()
+
+	start := time.Now()
+	rst, err := system.Require("a", "ping", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cycle, ok := rst.(*RequireCycleError)
+	if !ok {
+		t.Fatalf("expect a cycle error, got %v", rst)
+	}
+	if strings.Join(cycle.Cycle, " -> ") != "a -> b -> c -> a" {
+		t.Errorf("unexpected cycle %v", cycle)
+	}
+	if !strings.Contains(cycle.Error(), "waits-for graph: a -> b, b -> c") {
+		t.Errorf("expect the waits-for graph in %v", cycle)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("cycle detected too late, after %v", time.Since(start))
+	}
+}
+
+func TestRequireChainWithoutCycle(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("a", &relayActor{"b"})
+	system.AddActor("b", &relayActor{""})
+	defer system.Shutdown()
+
+	if rst, err := system.Require("a", "ping", 1000); err != nil || rst != "done" {
+		t.Errorf("unexpected response %v, %v", rst, err)
+	}
+}
+
+func TestRequireCycleThroughContext(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("a", &contextRelayActor{"b"})
+	system.AddActor("b", &contextRelayActor{"a"})
+	defer system.Shutdown()
+
+	rst, err := system.Require("a", "ping", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := rst.(*RequireCycleError); !ok {
+		t.Errorf("expect a cycle error, got %v", rst)
+	}
+}
+
+func TestRequireCycleThroughPlainReceive(t *testing.T) {
+	for _, dispatcher := range []Dispatcher{NewPinnedDispatcher(), NewPoolDispatcher(4, 10)} {
+		system := NewDefaultActorSystem()
+		system.SetDefaultDispatcher(dispatcher)
+		system.AddActor("a", &plainRelayActor{"b"})
+		system.AddActor("b", &plainRelayActor{"a"})
+
+		start := time.Now()
+		rst, err := system.Require("a", "ping", 1000)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		cycle, ok := rst.(*RequireCycleError)
+		if !ok {
+			t.Fatalf("expect a cycle error, got %v", rst)
+		}
+		if strings.Join(cycle.Cycle, " -> ") != "a -> b -> a" {
+			t.Errorf("unexpected cycle %v", cycle)
+		}
+		if time.Since(start) > 200*time.Millisecond {
+			t.Errorf("cycle detected too late, after %v", time.Since(start))
+		}
+		system.Shutdown()
+	}
+}
+
+type asyncCallerActor struct {
+	results chan *FutureResult
+}
+
+func (actor *asyncCallerActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *asyncCallerActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	switch typed := event.(type) {
+	case *FutureResult:
+		actor.results <- typed
+	case string:
+		if typed == "start" {
+			system.RequireAsync("b", "ping", 1000).PipeTo(system, "a", actor)
+		}
+	}
+	return "hi"
+}
+
+func (actor *asyncCallerActor) OnPullout(system *ActorSystem) {}
+
+func TestRequireAsyncNoCycle(t *testing.T) {
+	system := NewDefaultActorSystem()
+	a := &asyncCallerActor{make(chan *FutureResult, 1)}
+	system.AddActor("a", a)
+	system.AddActor("b", &plainRelayActor{"a"})
+	defer system.Shutdown()
+
+	system.Request("a", "start")
+	select {
+	case result := <-a.results:
+		if result.Error != nil || result.Result != "hi" {
+			t.Errorf("expect hi piped back, got %v, %v", result.Result, result.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("piped result never arrived")
+	}
+}
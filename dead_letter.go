@@ -1,18 +1,138 @@
 package goactor
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
+
+type DeadLetterReason int
+
+const (
+	DEAD_LETTER_NO_ROUTE DeadLetterReason = iota
+	DEAD_LETTER_TIMEOUT
+	DEAD_LETTER_MAILBOX_FULL
+	DEAD_LETTER_SHUTDOWN
+	DEAD_LETTER_PANIC
+	DEAD_LETTER_CANCELLED
+	DEAD_LETTER_ORPHANED
+	DEAD_LETTER_STOPPED
+	DEAD_LETTER_SERIALIZATION
+)
+
+var deadLetterReasonNames = []string{"no route", "timeout", "mailbox full", "shutdown", "panic", "cancelled", "orphaned response", "actor stopped", "serialization"}
+
+func (reason DeadLetterReason) String() string {
+	if int(reason) < len(deadLetterReasonNames) {
+		return deadLetterReasonNames[reason]
+	}
+	return fmt.Sprintf("DeadLetterReason(%d)", int(reason))
+}
+
+type DeadLetter struct {
+	ActorName     string
+	Event         interface{}
+	Reason        DeadLetterReason
+	Error         error
+	Sender        string
+	CorrelationID uint64
+	Timestamp     time.Time
+	// Elapsed is the time since a require was sent, zero for requests
+	Elapsed time.Duration
+	// Response is the late reply of an orphaned require
+	Response interface{}
+}
 
 type DeadLetterProcessor interface {
 	Process(actorName string, event interface{})
 }
 
+// ReasonedDeadLetterProcessor is picked over Process when implemented
+type ReasonedDeadLetterProcessor interface {
+	DeadLetterProcessor
+	ProcessWithReason(actorName string, event interface{}, reason error)
+}
+
+// DeadLetterRecorder is picked over both Process and ProcessWithReason when
+// implemented
+type DeadLetterRecorder interface {
+	DeadLetterProcessor
+	Record(letter *DeadLetter)
+}
+
 type ConsoleDeadLetterProcessor struct {
 }
 
 func (processor *ConsoleDeadLetterProcessor) Process(actorName string, event interface{}) {
-	fmt.Printf("Unabled to find actor \"%s\", discard event \"%+v\"", actorName, event)
+	fmt.Printf("Unabled to find actor \"%s\", discard event \"%+v\"\n", actorName, event)
+}
+
+func (processor *ConsoleDeadLetterProcessor) ProcessWithReason(actorName string, event interface{}, reason error) {
+	fmt.Printf("Discard event \"%+v\" to actor \"%s\": %v\n", event, actorName, reason)
+}
+
+func (processor *ConsoleDeadLetterProcessor) Record(letter *DeadLetter) {
+	fmt.Printf("%s discard event \"%+v\" from \"%s\" to actor \"%s\", %s: %v\n",
+		letter.Timestamp.Format(time.RFC3339), letter.Event, letter.Sender, letter.ActorName, letter.Reason, letter.Error)
 }
 
 func NewConsoleDeadLetterProcessor() *ConsoleDeadLetterProcessor {
 	return &ConsoleDeadLetterProcessor{}
 }
+
+func (system *ActorSystem) SetDeadLetterProcessor(processor DeadLetterProcessor) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	system.deadLetterProcessor = processor
+}
+
+func (system *ActorSystem) deadLetter(actorName string, event *Event, code DeadLetterReason, reason error) {
+	system.lock.RLock()
+	processor := system.deadLetterProcessor
+	system.lock.RUnlock()
+
+	switch p := processor.(type) {
+	case DeadLetterRecorder:
+		letter := &DeadLetter{
+			ActorName:     actorName,
+			Event:         event.event,
+			Reason:        code,
+			Error:         reason,
+			Sender:        event.sender,
+			CorrelationID: event.correlationID,
+			Timestamp:     time.Now(),
+		}
+		if !event.sentAt.IsZero() {
+			letter.Elapsed = letter.Timestamp.Sub(event.sentAt)
+		}
+		if orphan, ok := reason.(*OrphanedResponseError); ok {
+			letter.Response = orphan.Response
+		}
+		p.Record(letter)
+	case ReasonedDeadLetterProcessor:
+		p.ProcessWithReason(actorName, event.event, reason)
+	default:
+		p.Process(actorName, event.event)
+	}
+}
+
+// refuse dead-letters an event its sender failed to deliver, but a replayed
+// one, whose letter is still in the store
+func (system *ActorSystem) refuse(actorName string, event *Event, code DeadLetterReason, reason error) {
+	if !event.replayed {
+		system.deadLetter(actorName, event, code, reason)
+	}
+}
+
+// redeliver sends a dead letter again, failing quietly instead of making
+// another dead letter
+func (system *ActorSystem) redeliver(letter *DeadLetter) error {
+	if _, err := system.route(letter.ActorName, letter.Event); err != nil {
+		return err
+	}
+	return system.send(letter.ActorName, &Event{
+		event:         letter.Event,
+		sender:        letter.Sender,
+		correlationID: letter.CorrelationID,
+		replayed:      true,
+	})
+}
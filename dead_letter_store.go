@@ -0,0 +1,194 @@
+package goactor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DeadLetterStore keeps the latest dead letters in a ring buffer, and
+// optionally appends every one of them to a file as json lines
+type DeadLetterStore struct {
+	letters []*DeadLetter
+	head    int
+	count   int
+	dropped uint64
+
+	sink io.WriteCloser
+	// sinkErr is the first failed write, after which the sink is left alone
+	sinkErr error
+	lock    *sync.Mutex
+}
+
+func NewDeadLetterStore(capacity int) *DeadLetterStore {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	return &DeadLetterStore{
+		letters: make([]*DeadLetter, capacity),
+		lock:    &sync.Mutex{},
+	}
+}
+
+// NewFileDeadLetterStore is a DeadLetterStore which also appends to path
+func NewFileDeadLetterStore(capacity int, path string) (*DeadLetterStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	store := NewDeadLetterStore(capacity)
+	store.sink = file
+	return store, nil
+}
+
+func (store *DeadLetterStore) Process(actorName string, event interface{}) {
+	store.Record(&DeadLetter{
+		ActorName: actorName,
+		Event:     event,
+		Reason:    DEAD_LETTER_NO_ROUTE,
+		Timestamp: time.Now(),
+	})
+}
+
+func (store *DeadLetterStore) Record(letter *DeadLetter) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if store.count == len(store.letters) {
+		store.dropped++
+	} else {
+		store.count++
+	}
+	store.letters[store.head] = letter
+	store.head = (store.head + 1) % len(store.letters)
+
+	if store.sink != nil && store.sinkErr == nil {
+		if _, err := store.sink.Write(encodeDeadLetter(letter)); err != nil {
+			store.sinkErr = err
+		}
+	}
+}
+
+// Err tells why letters stopped being appended to the file, nil if they
+// still are
+func (store *DeadLetterStore) Err() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return store.sinkErr
+}
+
+type deadLetterLine struct {
+	Timestamp     time.Time `json:"timestamp"`
+	ActorName     string    `json:"actor"`
+	Reason        string    `json:"reason"`
+	Error         string    `json:"error,omitempty"`
+	Sender        string    `json:"sender,omitempty"`
+	CorrelationID uint64    `json:"correlation_id"`
+	EventType     string    `json:"event_type"`
+	Event         string    `json:"event"`
+}
+
+func encodeDeadLetter(letter *DeadLetter) []byte {
+	line := &deadLetterLine{
+		Timestamp:     letter.Timestamp,
+		ActorName:     letter.ActorName,
+		Reason:        letter.Reason.String(),
+		Sender:        letter.Sender,
+		CorrelationID: letter.CorrelationID,
+		Event:         fmt.Sprintf("%+v", letter.Event),
+	}
+	if letter.Error != nil {
+		line.Error = letter.Error.Error()
+	}
+	if letter.Event != nil {
+		line.EventType = reflect.TypeOf(letter.Event).String()
+	}
+	data, _ := json.Marshal(line)
+	return append(data, '\n')
+}
+
+// List returns the buffered dead letters, oldest first
+func (store *DeadLetterStore) List() []*DeadLetter {
+	return store.Filter(nil)
+}
+
+func (store *DeadLetterStore) Filter(filter func(letter *DeadLetter) bool) []*DeadLetter {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	letters := make([]*DeadLetter, 0, store.count)
+	start := (store.head - store.count + len(store.letters)) % len(store.letters)
+	for i := 0; i < store.count; i++ {
+		letter := store.letters[(start+i)%len(store.letters)]
+		if filter == nil || filter(letter) {
+			letters = append(letters, letter)
+		}
+	}
+	return letters
+}
+
+// Dropped tells how many letters were pushed out of the ring buffer
+func (store *DeadLetterStore) Dropped() uint64 {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return store.dropped
+}
+
+// Replay requests the matching dead letters again, e.g. once the missing
+// actor got registered. Replayed letters leave the store, the others stay.
+// A nil filter leaves out crashes and orphaned responses, which are not worth
+// sending again.
+func (store *DeadLetterStore) Replay(system *ActorSystem, filter func(letter *DeadLetter) bool) (replayed int) {
+	if filter == nil {
+		filter = replayable
+	}
+
+	done := make(map[*DeadLetter]bool)
+	for _, letter := range store.Filter(filter) {
+		if err := system.redeliver(letter); err == nil {
+			done[letter] = true
+		}
+	}
+	store.remove(done)
+	return len(done)
+}
+
+func replayable(letter *DeadLetter) bool {
+	return letter.Reason != DEAD_LETTER_PANIC && letter.Reason != DEAD_LETTER_ORPHANED
+}
+
+func (store *DeadLetterStore) remove(letters map[*DeadLetter]bool) {
+	if len(letters) == 0 {
+		return
+	}
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	start := (store.head - store.count + len(store.letters)) % len(store.letters)
+	kept := make([]*DeadLetter, 0, store.count)
+	for i := 0; i < store.count; i++ {
+		if l := store.letters[(start+i)%len(store.letters)]; !letters[l] {
+			kept = append(kept, l)
+		}
+	}
+
+	store.letters = make([]*DeadLetter, len(store.letters))
+	copy(store.letters, kept)
+	store.count = len(kept)
+	store.head = len(kept) % len(store.letters)
+}
+
+func (store *DeadLetterStore) Close() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.sink != nil {
+		if err := store.sink.Close(); err != nil {
+			return err
+		}
+	}
+	return store.sinkErr
+}
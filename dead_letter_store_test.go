@@ -0,0 +1,164 @@
+package goactor
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterStoreReplay(t *testing.T) {
+	store := NewDeadLetterStore(10)
+	system := NewDefaultActorSystem()
+	system.SetDeadLetterProcessor(store)
+	defer system.Shutdown()
+
+	system.Request("late", 1)
+	system.Request("late", 2)
+	system.Request("other", 3)
+
+	letters := store.Filter(func(letter *DeadLetter) bool { return letter.ActorName == "late" })
+	if len(letters) != 2 || letters[0].Event != 1 || letters[1].Event != 2 {
+		t.Fatalf("unexpected letters %+v", letters)
+	}
+	if letters[0].Reason != DEAD_LETTER_NO_ROUTE {
+		t.Errorf("expect no route, got %s", letters[0].Reason)
+	}
+
+	actor := newGateActor()
+	close(actor.gate)
+	system.AddActor("late", actor)
+
+	if replayed := store.Replay(system, nil); replayed != 2 {
+		t.Errorf("expect 2 replayed, got %d", replayed)
+	}
+	for _, expect := range []interface{}{1, 2} {
+		select {
+		case event := <-actor.received:
+			if event != expect {
+				t.Errorf("expect %v, got %v", expect, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("replayed event never arrived")
+		}
+	}
+
+	if left := store.List(); len(left) != 1 || left[0].ActorName != "other" {
+		t.Errorf("unexpected letters left %+v", left)
+	}
+}
+
+func TestDeadLetterStoreRing(t *testing.T) {
+	store := NewDeadLetterStore(3)
+	for i := 0; i < 5; i++ {
+		store.Record(&DeadLetter{ActorName: "a", Event: i})
+	}
+
+	letters := store.List()
+	if len(letters) != 3 || letters[0].Event != 2 || letters[2].Event != 4 {
+		t.Errorf("unexpected letters %+v", letters)
+	}
+	if store.Dropped() != 2 {
+		t.Errorf("expect 2 dropped, got %d", store.Dropped())
+	}
+}
+
+func TestDeadLetterStoreFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "letters.jsonl")
+
+	store, err := NewFileDeadLetterStore(10, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Record(&DeadLetter{ActorName: "a", Event: "x", Reason: DEAD_LETTER_MAILBOX_FULL, Error: ErrMailboxFull})
+	store.Record(&DeadLetter{ActorName: "b", Event: "y", Reason: DEAD_LETTER_SHUTDOWN})
+	store.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"reason":"mailbox full"`) || !strings.Contains(lines[1], `"actor":"b"`) {
+		t.Errorf("unexpected file content %s", data)
+	}
+}
+
+func TestDeadLetterStoreReplaySkips(t *testing.T) {
+	store := NewDeadLetterStore(10)
+	system := NewDefaultActorSystem()
+	system.SetDeadLetterProcessor(store)
+	defer system.Shutdown()
+
+	actor := newGateActor()
+	close(actor.gate)
+	system.AddActor("late", actor)
+	store.Record(&DeadLetter{ActorName: "late", Event: 1, Reason: DEAD_LETTER_PANIC})
+	store.Record(&DeadLetter{ActorName: "late", Event: 2, Reason: DEAD_LETTER_ORPHANED})
+	store.Record(&DeadLetter{ActorName: "late", Event: 3, Reason: DEAD_LETTER_NO_ROUTE})
+
+	if replayed := store.Replay(system, nil); replayed != 1 {
+		t.Errorf("expect 1 replayed, got %d", replayed)
+	}
+	if event := <-actor.received; event != 3 {
+		t.Errorf("expect 3, got %v", event)
+	}
+	if left := store.List(); len(left) != 2 {
+		t.Errorf("unexpected letters left %+v", left)
+	}
+
+	if replayed := store.Replay(system, func(letter *DeadLetter) bool { return true }); replayed != 2 {
+		t.Errorf("expect crashes replayed when asked, got %d", replayed)
+	}
+}
+
+func TestDeadLetterStoreReplayRefused(t *testing.T) {
+	store := NewDeadLetterStore(10)
+	system := NewDefaultActorSystem()
+	system.SetDeadLetterProcessor(store)
+	actor := newGateActor()
+	system.AddActor("bounded", actor, WithMailbox(1, OVERFLOW_DEAD_LETTER))
+	defer system.Shutdown()
+
+	fillMailbox(t, system, 1)
+	system.Request("bounded", 2)
+	if replayed := store.Replay(system, nil); replayed != 0 {
+		t.Errorf("expect nothing replayed into a full mailbox, got %d", replayed)
+	}
+	if left := store.List(); len(left) != 1 || left[0].Event != 2 {
+		t.Errorf("expect the letter kept once, got %+v", left)
+	}
+	collect(actor, 2)
+}
+
+type failingSink struct{}
+
+func (sink failingSink) Write(data []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func (sink failingSink) Close() error {
+	return nil
+}
+
+func TestDeadLetterStoreSinkError(t *testing.T) {
+	store := NewDeadLetterStore(10)
+	store.sink = failingSink{}
+	store.Record(&DeadLetter{ActorName: "a", Event: "x"})
+
+	if err := store.Err(); err == nil || err.Error() != "disk full" {
+		t.Errorf("expect write error, got %v", err)
+	}
+	if err := store.Close(); err == nil {
+		t.Error("expect write error on close")
+	}
+	if len(store.List()) != 1 {
+		t.Error("letter lost with its file")
+	}
+}
@@ -0,0 +1,162 @@
+package goactor
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	queue "github.com/scryner/lfreequeue"
+)
+
+// Dispatcher decides which goroutine runs an actor. Dispatch is called once
+// the actor is added, Notify whenever something is put into its mailbox.
+type Dispatcher interface {
+	Dispatch(actor *innerActor)
+	Notify(actor *innerActor)
+}
+
+// PinnedDispatcher gives every actor a goroutine of its own, which waits
+// for its mailbox even when idle
+type PinnedDispatcher struct {
+}
+
+func NewPinnedDispatcher() *PinnedDispatcher {
+	return &PinnedDispatcher{}
+}
+
+func (dispatcher *PinnedDispatcher) Dispatch(actor *innerActor) {
+	go actor.loop()
+}
+
+func (dispatcher *PinnedDispatcher) Notify(actor *innerActor) {
+	select {
+	case actor.notifyChan <- nil:
+	default:
+	}
+}
+
+// PoolDispatcher shares a fixed set of workers among its actors, an idle
+// actor costs no goroutine. A worker handles at most throughput events of an
+// actor before moving on to the next one with pending mail.
+//
+// A worker blocked in Receive, e.g. on a Require, is lost to the others until
+// it returns, so a few such actors starve the whole pool. Give actors which
+// block a PinnedDispatcher of their own with SetDispatcher.
+type PoolDispatcher struct {
+	workers    int
+	throughput int
+
+	ready  *queue.Queue
+	signal chan struct{}
+	done   chan struct{}
+	closed int32
+}
+
+func NewPoolDispatcher(workers int, throughput int) *PoolDispatcher {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if throughput <= 0 {
+		throughput = 100
+	}
+
+	dispatcher := &PoolDispatcher{
+		workers:    workers,
+		throughput: throughput,
+		ready:      queue.NewQueue(),
+		signal:     make(chan struct{}, workers),
+		done:       make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go dispatcher.work()
+	}
+	return dispatcher
+}
+
+func (dispatcher *PoolDispatcher) Dispatch(actor *innerActor) {
+	// scheduled right away, so OnPlugin runs even without mail
+	atomic.StoreInt32(&actor.scheduled, 1)
+	dispatcher.schedule(actor)
+}
+
+func (dispatcher *PoolDispatcher) Notify(actor *innerActor) {
+	if atomic.CompareAndSwapInt32(&actor.scheduled, 0, 1) {
+		dispatcher.schedule(actor)
+	}
+}
+
+func (dispatcher *PoolDispatcher) schedule(actor *innerActor) {
+	dispatcher.ready.Enqueue(actor)
+	select {
+	case dispatcher.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Close lets the workers go once no actor is left with pending mail, so
+// actors told to stop still get to. Shutdown closes the pools of the system,
+// a pool is not to be shared by systems.
+func (dispatcher *PoolDispatcher) Close() {
+	if atomic.CompareAndSwapInt32(&dispatcher.closed, 0, 1) {
+		close(dispatcher.done)
+	}
+}
+
+func (dispatcher *PoolDispatcher) work() {
+	for {
+		next, ok := dispatcher.ready.Dequeue()
+		if !ok {
+			// nothing left to run, the actors of a closed pool are stopped
+			if atomic.LoadInt32(&dispatcher.closed) != 0 {
+				return
+			}
+			select {
+			case <-dispatcher.signal:
+			case <-dispatcher.done:
+			}
+			continue
+		}
+
+		actor := next.(*innerActor)
+		stopped := actor.run(dispatcher.throughput)
+		if stopped {
+			// stopped, stays scheduled to never run again
+			continue
+		}
+
+		atomic.StoreInt32(&actor.scheduled, 0)
+		// mail may have arrived after run gave up, with its Notify lost
+		if actor.pending() && atomic.CompareAndSwapInt32(&actor.scheduled, 0, 1) {
+			dispatcher.schedule(actor)
+		}
+	}
+}
+
+// SetDispatcher picks the dispatcher of actors added as name from now on,
+// nil for the default one
+func (system *ActorSystem) SetDispatcher(name string, dispatcher Dispatcher) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	if dispatcher == nil {
+		delete(system.dispatchers, name)
+	} else {
+		system.dispatchers[name] = dispatcher
+	}
+}
+
+func (system *ActorSystem) SetDefaultDispatcher(dispatcher Dispatcher) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	system.defaultDispatcher = dispatcher
+}
+
+// closeDispatchers requires system.lock held
+func (system *ActorSystem) closeDispatchers() {
+	if pool, ok := system.defaultDispatcher.(*PoolDispatcher); ok {
+		pool.Close()
+	}
+	for _, dispatcher := range system.dispatchers {
+		if pool, ok := dispatcher.(*PoolDispatcher); ok {
+			pool.Close()
+		}
+	}
+}
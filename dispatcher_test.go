@@ -0,0 +1,98 @@
+package goactor
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolDispatcher(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.SetDefaultDispatcher(NewPoolDispatcher(4, 10))
+	before := runtime.NumGoroutine()
+
+	actors := make([]*slowActor, 1000)
+	for i := range actors {
+		actors[i] = &slowActor{0, new(int32), new(int32)}
+		system.AddActor(fmt.Sprintf("entity-%d", i), actors[i])
+	}
+	if after := runtime.NumGoroutine(); after-before > 10 {
+		t.Errorf("expect idle actors to share the pool, %d goroutines started", after-before)
+	}
+
+	for i := range actors {
+		if rst, err := system.Require(fmt.Sprintf("entity-%d", i), i, 1000); err != nil || rst != i {
+			t.Fatalf("unexpected response %v, %v", rst, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := system.ShutdownGracefully(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	for i, actor := range actors {
+		if atomic.LoadInt32(actor.pullouts) != 1 {
+			t.Fatalf("actor %d not pulled out", i)
+		}
+	}
+}
+
+func TestPoolDispatcherFairness(t *testing.T) {
+	system := NewDefaultActorSystem()
+	pool := NewPoolDispatcher(1, 1)
+	system.SetDispatcher("busy", pool)
+	system.SetDispatcher("quick", pool)
+	system.AddActor("busy", &slowActor{5 * time.Millisecond, new(int32), new(int32)})
+	system.AddActor("quick", &slowActor{0, new(int32), new(int32)})
+	pinned := &slowActor{0, new(int32), new(int32)}
+	system.AddActor("pinned", pinned)
+	defer system.Shutdown()
+
+	for i := 0; i < 100; i++ {
+		system.Request("busy", i)
+	}
+	if _, err := system.Require("quick", "hello", 100); err != nil {
+		t.Errorf("quick actor starved by busy one: %v", err)
+	}
+	if _, err := system.Require("pinned", "hello", 100); err != nil {
+		t.Errorf("pinned actor failed: %v", err)
+	}
+}
+
+func TestPoolDispatcherClosedOnShutdown(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.SetDefaultDispatcher(NewPoolDispatcher(3, 10))
+	actors := make([]*slowActor, 10)
+	for i := range actors {
+		actors[i] = &slowActor{time.Millisecond, new(int32), new(int32)}
+		system.AddActor(fmt.Sprintf("entity-%d", i), actors[i])
+		system.Request(fmt.Sprintf("entity-%d", i), i)
+	}
+	system.Shutdown()
+
+	stack := make([]byte, 1<<20)
+	for deadline := time.Now().Add(time.Second); ; {
+		n := runtime.Stack(stack, true)
+		if !strings.Contains(string(stack[:n]), "(*PoolDispatcher).work") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pool workers still running after shutdown")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i, actor := range actors {
+		if atomic.LoadInt32(actor.pullouts) != 1 {
+			t.Errorf("actor %d not pulled out", i)
+		}
+	}
+
+	if ok, err := system.AddActor("late", &slowActor{0, new(int32), new(int32)}); ok || err != ErrSystemShutdown {
+		t.Errorf("expect no actor added after shutdown, got %v, %v", ok, err)
+	}
+}
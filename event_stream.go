@@ -0,0 +1,156 @@
+package goactor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// eventStream is the system level event bus. Subscriptions belong to actor
+// instances and go away together with them.
+type eventStream struct {
+	topics map[string]map[*innerActor]struct{}
+	types  map[reflect.Type]map[*innerActor]struct{}
+	lock   *sync.RWMutex
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{
+		topics: make(map[string]map[*innerActor]struct{}),
+		types:  make(map[reflect.Type]map[*innerActor]struct{}),
+		lock:   &sync.RWMutex{},
+	}
+}
+
+func (stream *eventStream) forget(actor *innerActor) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	for topic, subscribers := range stream.topics {
+		delete(subscribers, actor)
+		if len(subscribers) == 0 {
+			delete(stream.topics, topic)
+		}
+	}
+	for eventType, subscribers := range stream.types {
+		delete(subscribers, actor)
+		if len(subscribers) == 0 {
+			delete(stream.types, eventType)
+		}
+	}
+}
+
+func (stream *eventStream) clear() {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	stream.topics = make(map[string]map[*innerActor]struct{})
+	stream.types = make(map[reflect.Type]map[*innerActor]struct{})
+}
+
+func (system *ActorSystem) subscriber(actorName string, actorImpl ActorInterface) (*innerActor, error) {
+	if actor := system.instance(actorName, actorImpl); actor != nil {
+		return actor, nil
+	}
+	return nil, errors.New(fmt.Sprintf("actor %s not in system", actorName))
+}
+
+// Subscribe makes the actor instance receive every event published to topic
+func (system *ActorSystem) Subscribe(actorName string, actorImpl ActorInterface, topic string) error {
+	actor, err := system.subscriber(actorName, actorImpl)
+	if err != nil {
+		return err
+	}
+
+	stream := system.stream
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	if _, ok := stream.topics[topic]; !ok {
+		stream.topics[topic] = make(map[*innerActor]struct{})
+	}
+	stream.topics[topic][actor] = struct{}{}
+	return nil
+}
+
+// SubscribeType makes the actor instance receive every published event of
+// eventType, whatever the topic
+func (system *ActorSystem) SubscribeType(actorName string, actorImpl ActorInterface, eventType reflect.Type) error {
+	actor, err := system.subscriber(actorName, actorImpl)
+	if err != nil {
+		return err
+	}
+
+	stream := system.stream
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	if _, ok := stream.types[eventType]; !ok {
+		stream.types[eventType] = make(map[*innerActor]struct{})
+	}
+	stream.types[eventType][actor] = struct{}{}
+	return nil
+}
+
+func (system *ActorSystem) Unsubscribe(actorName string, actorImpl ActorInterface, topic string) {
+	if actor := system.instance(actorName, actorImpl); actor != nil {
+		stream := system.stream
+		stream.lock.Lock()
+		defer stream.lock.Unlock()
+		if subscribers, ok := stream.topics[topic]; ok {
+			delete(subscribers, actor)
+			if len(subscribers) == 0 {
+				delete(stream.topics, topic)
+			}
+		}
+	}
+}
+
+func (system *ActorSystem) UnsubscribeType(actorName string, actorImpl ActorInterface, eventType reflect.Type) {
+	if actor := system.instance(actorName, actorImpl); actor != nil {
+		stream := system.stream
+		stream.lock.Lock()
+		defer stream.lock.Unlock()
+		if subscribers, ok := stream.types[eventType]; ok {
+			delete(subscribers, actor)
+			if len(subscribers) == 0 {
+				delete(stream.types, eventType)
+			}
+		}
+	}
+}
+
+// Publish delivers event to the subscribers of topic and of the event's
+// type, each instance once, and returns how many got it. An empty topic
+// publishes by type only.
+func (system *ActorSystem) Publish(topic string, event interface{}) int {
+	if atomic.LoadInt32(&system.stopping) != 0 {
+		return 0
+	}
+
+	stream := system.stream
+	stream.lock.RLock()
+	subscribers := make(map[*innerActor]struct{})
+	if topic != "" {
+		for actor := range stream.topics[topic] {
+			subscribers[actor] = struct{}{}
+		}
+	}
+	if event != nil {
+		for actor := range stream.types[reflect.TypeOf(event)] {
+			subscribers[actor] = struct{}{}
+		}
+	}
+	stream.lock.RUnlock()
+
+	correlationID := atomic.AddUint64(&correlationSeq, 1)
+	delivered := 0
+	for actor := range subscribers {
+		if err := actor.push(&Event{
+			event:         event,
+			responseChan:  nil,
+			correlationID: correlationID,
+		}); err == nil {
+			delivered++
+		}
+	}
+	return delivered
+}
@@ -0,0 +1,77 @@
+package goactor
+
+import (
+	"reflect"
+	"testing"
+)
+
+type orderPlaced struct {
+	id int
+}
+
+type subscriberActor struct {
+	topic  string
+	events chan interface{}
+}
+
+func (actor *subscriberActor) OnPlugin(system *ActorSystem) {
+	system.Subscribe("subscriber", actor, actor.topic)
+}
+
+func (actor *subscriberActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	actor.events <- event
+	return nil
+}
+
+func (actor *subscriberActor) OnPullout(system *ActorSystem) {}
+
+func TestPublishSubscribe(t *testing.T) {
+	system := NewActorSystem(NewFullQualifiedNameWithCustomBalancerRouter(NewRoundRobinBalancer()), NewConsoleDeadLetterProcessor())
+	orders := &subscriberActor{"orders", make(chan interface{}, 10)}
+	payments := &subscriberActor{"payments", make(chan interface{}, 10)}
+	system.AddActor("subscriber", orders)
+	system.AddActor("subscriber", payments)
+	defer system.Shutdown()
+
+	// subscribing in OnPlugin happens on the actor goroutine, a require to
+	// each instance makes sure it is done
+	system.Require("subscriber", nil, 1000)
+	system.Require("subscriber", nil, 1000)
+	drain := func(actor *subscriberActor) {
+		for len(actor.events) > 0 {
+			<-actor.events
+		}
+	}
+	drain(orders)
+	drain(payments)
+
+	if delivered := system.Publish("orders", "placed"); delivered != 1 {
+		t.Errorf("expect 1 delivery, got %d", delivered)
+	}
+	if event := <-orders.events; event != "placed" {
+		t.Errorf("unexpected event %v", event)
+	}
+
+	if err := system.SubscribeType("subscriber", payments, reflect.TypeOf(&orderPlaced{})); err != nil {
+		t.Fatal(err)
+	}
+	if delivered := system.Publish("orders", &orderPlaced{1}); delivered != 2 {
+		t.Errorf("expect delivery to topic and type subscriber, got %d", delivered)
+	}
+	<-orders.events
+	<-payments.events
+
+	system.Unsubscribe("subscriber", orders, "orders")
+	if delivered := system.Publish("orders", "placed"); delivered != 0 {
+		t.Errorf("unsubscribed actor still delivered, got %d", delivered)
+	}
+
+	system.RemoveActor("subscriber", payments)
+	if delivered := system.Publish("", &orderPlaced{2}); delivered != 0 {
+		t.Errorf("removed actor still subscribed, got %d", delivered)
+	}
+
+	if err := system.Subscribe("subscriber", payments, "orders"); err == nil {
+		t.Error("removed actor should not be able to subscribe")
+	}
+}
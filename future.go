@@ -0,0 +1,182 @@
+package goactor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type Future struct {
+	done      chan struct{}
+	result    interface{}
+	err       error
+	lock      sync.Mutex
+	callbacks []func(result interface{}, err error)
+}
+
+// FutureResult is what PipeTo delivers into the actor's mailbox
+type FutureResult struct {
+	Future *Future
+	Result interface{}
+	Error  error
+}
+
+func newFuture() *Future {
+	return &Future{
+		done: make(chan struct{}),
+	}
+}
+
+func NewCompletedFuture(result interface{}, err error) *Future {
+	future := newFuture()
+	future.complete(result, err)
+	return future
+}
+
+func (future *Future) complete(result interface{}, err error) bool {
+	future.lock.Lock()
+	select {
+	case <-future.done:
+		future.lock.Unlock()
+		return false
+	default:
+	}
+	future.result, future.err = result, err
+	close(future.done)
+	callbacks := future.callbacks
+	future.callbacks = nil
+	future.lock.Unlock()
+
+	for _, callback := range callbacks {
+		callback(result, err)
+	}
+	return true
+}
+
+func (future *Future) Done() <-chan struct{} {
+	return future.done
+}
+
+func (future *Future) Await() (interface{}, error) {
+	<-future.done
+	return future.result, future.err
+}
+
+// OnComplete runs callback on the completing goroutine, or right away if
+// the future is already completed
+func (future *Future) OnComplete(callback func(result interface{}, err error)) {
+	future.lock.Lock()
+	select {
+	case <-future.done:
+		future.lock.Unlock()
+		callback(future.result, future.err)
+	default:
+		future.callbacks = append(future.callbacks, callback)
+		future.lock.Unlock()
+	}
+}
+
+func (future *Future) Then(fn func(result interface{}) (interface{}, error)) *Future {
+	next := newFuture()
+	future.OnComplete(func(result interface{}, err error) {
+		if err != nil {
+			next.complete(nil, err)
+		} else {
+			next.complete(fn(result))
+		}
+	})
+	return next
+}
+
+// PipeTo delivers the outcome as a FutureResult to the very instance
+// registered as actorImpl under actorName
+func (future *Future) PipeTo(system *ActorSystem, actorName string, actorImpl ActorInterface) {
+	future.OnComplete(func(result interface{}, err error) {
+		piped := &Event{event: &FutureResult{future, result, err}}
+		if actor := system.instance(actorName, actorImpl); actor == nil {
+			system.deadLetter(actorName, piped, DEAD_LETTER_NO_ROUTE, errors.New(fmt.Sprintf("actor %s not in system", actorName)))
+		} else if err := actor.push(piped); err != nil {
+			switch {
+			case err == ErrActorStopped:
+				system.deadLetter(actorName, piped, DEAD_LETTER_STOPPED, err)
+			case actor.overflow != OVERFLOW_DEAD_LETTER:
+				// the dead letter policy has recorded it already
+				system.deadLetter(actorName, piped, DEAD_LETTER_MAILBOX_FULL, err)
+			}
+		}
+	})
+}
+
+func All(futures ...*Future) *Future {
+	all := newFuture()
+	results := make([]interface{}, len(futures))
+	if len(futures) == 0 {
+		all.complete(results, nil)
+		return all
+	}
+
+	var lock sync.Mutex
+	remain := len(futures)
+	for i, future := range futures {
+		index := i
+		future.OnComplete(func(result interface{}, err error) {
+			if err != nil {
+				all.complete(nil, err)
+				return
+			}
+			lock.Lock()
+			results[index] = result
+			remain--
+			finished := remain == 0
+			lock.Unlock()
+			if finished {
+				all.complete(results, nil)
+			}
+		})
+	}
+	return all
+}
+
+// Any completes with the first success, or the last error if all failed
+func Any(futures ...*Future) *Future {
+	anyOf := newFuture()
+	if len(futures) == 0 {
+		anyOf.complete(nil, errors.New("no future to wait"))
+		return anyOf
+	}
+
+	var lock sync.Mutex
+	remain := len(futures)
+	for _, future := range futures {
+		future.OnComplete(func(result interface{}, err error) {
+			if err == nil {
+				anyOf.complete(result, nil)
+				return
+			}
+			lock.Lock()
+			remain--
+			finished := remain == 0
+			lock.Unlock()
+			if finished {
+				anyOf.complete(nil, err)
+			}
+		})
+	}
+	return anyOf
+}
+
+// FirstOf completes with whichever future completes first, success or not
+func FirstOf(futures ...*Future) *Future {
+	first := newFuture()
+	if len(futures) == 0 {
+		first.complete(nil, errors.New("no future to wait"))
+		return first
+	}
+
+	for _, future := range futures {
+		future.OnComplete(func(result interface{}, err error) {
+			first.complete(result, err)
+		})
+	}
+	return first
+}
@@ -0,0 +1,143 @@
+package goactor
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRequireAsync(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("echo", new(mockActor))
+	defer system.Shutdown()
+
+	future := system.RequireAsync("echo", 1, 1000).Then(func(result interface{}) (interface{}, error) {
+		return result.(int) + 1, nil
+	})
+	if rst, err := future.Await(); rst != 2 || err != nil {
+		t.Errorf("expect 2, got %v, %v", rst, err)
+	}
+
+	if _, err := system.RequireAsync("missing", 1, 1000).Await(); err == nil {
+		t.Error("expect routing error")
+	}
+
+	completed := make(chan interface{}, 1)
+	system.RequireAsync("echo", 3, 1000).OnComplete(func(result interface{}, err error) {
+		completed <- result
+	})
+	if rst := <-completed; rst != 3 {
+		t.Errorf("expect 3 on complete, got %v", rst)
+	}
+}
+
+func TestFutureCombinators(t *testing.T) {
+	failed := NewCompletedFuture(nil, errors.New("failed"))
+	pending := newFuture()
+
+	if rst, err := All(NewCompletedFuture(1, nil), NewCompletedFuture(2, nil)).Await(); err != nil || rst.([]interface{})[1] != 2 {
+		t.Errorf("unexpected all result %v, %v", rst, err)
+	}
+	if _, err := All(NewCompletedFuture(1, nil), failed, pending).Await(); err == nil {
+		t.Error("all should fail on first error")
+	}
+
+	if rst, err := Any(failed, NewCompletedFuture(1, nil), pending).Await(); rst != 1 || err != nil {
+		t.Errorf("unexpected any result %v, %v", rst, err)
+	}
+	if _, err := Any(failed, failed).Await(); err == nil {
+		t.Error("any should fail when all failed")
+	}
+
+	first := FirstOf(pending, newFuture())
+	go pending.complete(5, nil)
+	select {
+	case <-first.Done():
+		if rst, _ := first.Await(); rst != 5 {
+			t.Errorf("expect 5 first, got %v", rst)
+		}
+	case <-time.After(time.Second):
+		t.Error("first of never completed")
+	}
+}
+
+type orchestratorActor struct {
+	results chan *FutureResult
+}
+
+func (actor *orchestratorActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *orchestratorActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	switch e := event.(type) {
+	case *FutureResult:
+		actor.results <- e
+	default:
+		system.RequireAsync("echo", e, 1000).PipeTo(system, "orchestrator", actor)
+	}
+	return nil
+}
+
+func (actor *orchestratorActor) OnPullout(system *ActorSystem) {}
+
+func TestFuturePipeTo(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("echo", new(mockActor))
+	actor := &orchestratorActor{make(chan *FutureResult, 1)}
+	system.AddActor("orchestrator", actor)
+	defer system.Shutdown()
+
+	system.Request("orchestrator", "ping")
+	select {
+	case piped := <-actor.results:
+		if piped.Result != "ping" || piped.Error != nil {
+			t.Errorf("unexpected piped result %+v", piped)
+		}
+	case <-time.After(time.Second):
+		t.Error("result never piped back")
+	}
+}
+
+func pipedLetters(store *DeadLetterStore) []*DeadLetter {
+	return store.Filter(func(letter *DeadLetter) bool {
+		_, ok := letter.Event.(*FutureResult)
+		return ok
+	})
+}
+
+func TestFuturePipeToRefused(t *testing.T) {
+	for _, overflow := range []OverflowPolicy{OVERFLOW_DROP_NEWEST, OVERFLOW_DEAD_LETTER} {
+		store := NewDeadLetterStore(10)
+		system := NewActorSystem(NewFullQualifiedNameWithRandomBalancerRouter(), store)
+		actor := newGateActor()
+		system.AddActor("bounded", actor, WithMailbox(2, overflow))
+
+		fillMailbox(t, system, 2)
+		NewCompletedFuture("x", nil).PipeTo(system, "bounded", actor)
+		if letters := pipedLetters(store); len(letters) != 1 || letters[0].Reason != DEAD_LETTER_MAILBOX_FULL {
+			t.Errorf("expect one mailbox full dead letter with %v, got %v", overflow, letters)
+		}
+		collect(actor, 3)
+		system.Shutdown()
+	}
+
+	// a sender blocked on the full mailbox of an actor going away
+	store := NewDeadLetterStore(10)
+	system := NewActorSystem(NewFullQualifiedNameWithRandomBalancerRouter(), store)
+	actor := newGateActor()
+	system.AddActor("bounded", actor, WithMailbox(2, OVERFLOW_BLOCK))
+	defer system.Shutdown()
+
+	fillMailbox(t, system, 2)
+	piping := make(chan struct{})
+	go func() {
+		NewCompletedFuture("x", nil).PipeTo(system, "bounded", actor)
+		close(piping)
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	system.RemoveActor("bounded", actor)
+	collect(actor, 1)
+	<-piping
+	if letters := pipedLetters(store); len(letters) != 1 || letters[0].Reason != DEAD_LETTER_STOPPED {
+		t.Errorf("expect one stopped dead letter, got %v", letters)
+	}
+}
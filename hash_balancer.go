@@ -0,0 +1,146 @@
+package goactor
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// EventBalancer is picked over Choose when implemented, for balancers which
+// need the event itself to decide
+type EventBalancer interface {
+	Balancer
+	ChooseEvent(actorName string, event interface{}, actors []*innerActor) *innerActor
+}
+
+// HashKeyer lets an event tell the key ConsistentHashBalancer routes on
+type HashKeyer interface {
+	HashKey() string
+}
+
+type hashRing struct {
+	// actors is the registry slice the ring was built from, the registry
+	// replaces it on any change of the name
+	actors []*innerActor
+	points []uint64
+	owners map[uint64]*innerActor
+}
+
+func (ring *hashRing) builtFrom(actors []*innerActor) bool {
+	return len(ring.actors) == len(actors) && len(actors) > 0 && &ring.actors[0] == &actors[0]
+}
+
+// sameMembers tells whether actors holds the same instances, in any order
+func (ring *hashRing) sameMembers(actors []*innerActor) bool {
+	if len(ring.actors) != len(actors) {
+		return false
+	}
+	members := make(map[*innerActor]struct{}, len(ring.actors))
+	for _, actor := range ring.actors {
+		members[actor] = struct{}{}
+	}
+	for _, actor := range actors {
+		if _, ok := members[actor]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ConsistentHashBalancer sends every event of the same key to the same
+// instance, only keys of an added/removed instance get remapped. Events
+// without key are balanced randomly.
+type ConsistentHashBalancer struct {
+	virtualNodes int
+	keyOf        func(event interface{}) (string, bool)
+	random       RandomBalancer
+
+	rings *sync.Map // actor name -> *hashRing
+}
+
+func NewConsistentHashBalancer(virtualNodes int, keyOf func(event interface{}) (string, bool)) Balancer {
+	if virtualNodes <= 0 {
+		virtualNodes = 100
+	}
+	return &ConsistentHashBalancer{
+		virtualNodes: virtualNodes,
+		keyOf:        keyOf,
+		random:       *NewRandomBalancer().(*RandomBalancer),
+		rings:        &sync.Map{},
+	}
+}
+
+func (balancer *ConsistentHashBalancer) Choose(actorName string, actors []*innerActor) *innerActor {
+	return balancer.random.Choose(actorName, actors)
+}
+
+func (balancer *ConsistentHashBalancer) ChooseEvent(actorName string, event interface{}, actors []*innerActor) *innerActor {
+	key, ok := balancer.key(event)
+	if !ok {
+		return balancer.Choose(actorName, actors)
+	}
+
+	ring := balancer.ring(actorName, actors)
+	hash := hashOf(key)
+	index := sort.Search(len(ring.points), func(i int) bool { return ring.points[i] >= hash })
+	if index == len(ring.points) {
+		index = 0
+	}
+	return ring.owners[ring.points[index]]
+}
+
+func (balancer *ConsistentHashBalancer) key(event interface{}) (string, bool) {
+	if balancer.keyOf != nil {
+		if key, ok := balancer.keyOf(event); ok {
+			return key, true
+		}
+	}
+	if keyer, ok := event.(HashKeyer); ok {
+		return keyer.HashKey(), true
+	}
+	return "", false
+}
+
+// ring is rebuilt only when the instances under actorName changed
+func (balancer *ConsistentHashBalancer) ring(actorName string, actors []*innerActor) *hashRing {
+	var ring *hashRing
+	if cached, ok := balancer.rings.Load(actorName); ok {
+		ring = cached.(*hashRing)
+		if ring.builtFrom(actors) {
+			return ring
+		}
+	}
+
+	if ring != nil && ring.sameMembers(actors) {
+		ring = &hashRing{actors, ring.points, ring.owners}
+	} else {
+		ring = &hashRing{
+			actors: actors,
+			points: make([]uint64, 0, len(actors)*balancer.virtualNodes),
+			owners: make(map[uint64]*innerActor, len(actors)*balancer.virtualNodes),
+		}
+		for _, actor := range actors {
+			for v := 0; v < balancer.virtualNodes; v++ {
+				point := hashOf(strconv.FormatUint(actor.id, 10) + "#" + strconv.Itoa(v))
+				if _, ok := ring.owners[point]; !ok {
+					ring.points = append(ring.points, point)
+				}
+				ring.owners[point] = actor
+			}
+		}
+		sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	}
+	balancer.rings.Store(actorName, ring)
+	return ring
+}
+
+func (balancer *ConsistentHashBalancer) forget(actorName string) {
+	balancer.rings.Delete(actorName)
+}
+
+func hashOf(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
@@ -0,0 +1,177 @@
+package goactor
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+type OverflowPolicy int
+
+const (
+	OVERFLOW_BLOCK OverflowPolicy = iota
+	OVERFLOW_DROP_NEWEST
+	OVERFLOW_DROP_OLDEST
+	OVERFLOW_DEAD_LETTER
+)
+
+var (
+	ErrMailboxFull  = errors.New("mailbox is full")
+	ErrActorStopped = errors.New("actor stopped")
+)
+
+func WithMailbox(capacity int, policy OverflowPolicy) ActorOption {
+	return func(actor *innerActor) {
+		actor.capacity = capacity
+		actor.overflow = policy
+	}
+}
+
+func (actor *innerActor) reserve(event *Event) error {
+	for {
+		size := atomic.LoadInt32(&actor.size)
+		if int(size) < actor.capacity {
+			if atomic.CompareAndSwapInt32(&actor.size, size, size+1) {
+				return nil
+			}
+			continue
+		}
+
+		switch actor.overflow {
+		case OVERFLOW_BLOCK:
+			if err := actor.waitNotFull(event); err != nil {
+				return err
+			}
+		case OVERFLOW_DROP_OLDEST:
+			if oldest, ok := actor.evictEvent(); ok {
+				if _, ok := oldest.event.(ExitEvent); ok {
+					// never drop an exit, it overtakes the mailbox instead
+					actor.signal(oldest)
+					continue
+				}
+				oldest.respond(&failedResponse{ErrMailboxFull})
+				actor.system.deadLetter(actor.name, oldest, DEAD_LETTER_MAILBOX_FULL, ErrMailboxFull)
+			}
+		case OVERFLOW_DEAD_LETTER:
+			actor.system.refuse(actor.name, event, DEAD_LETTER_MAILBOX_FULL, ErrMailboxFull)
+			return ErrMailboxFull
+		default:
+			return ErrMailboxFull
+		}
+	}
+}
+
+// waitNotFull blocks the sender of event until there is room, the actor
+// stopped or the ctx of event is done
+func (actor *innerActor) waitNotFull(event *Event) error {
+	if event.ctx != nil {
+		waiting := make(chan struct{})
+		defer close(waiting)
+		go func() {
+			select {
+			case <-event.ctx.Done():
+				actor.mailboxLock.Lock()
+				actor.notFull.Broadcast()
+				actor.mailboxLock.Unlock()
+			case <-waiting:
+			}
+		}()
+	}
+
+	actor.mailboxLock.Lock()
+	defer actor.mailboxLock.Unlock()
+	for int(atomic.LoadInt32(&actor.size)) >= actor.capacity && atomic.LoadInt32(&actor.closed) == 0 {
+		if event.ctx != nil && event.ctx.Err() != nil {
+			return event.ctx.Err()
+		}
+		actor.notFull.Wait()
+	}
+	if atomic.LoadInt32(&actor.closed) != 0 {
+		return ErrActorStopped
+	}
+	return nil
+}
+
+// dropsOldest tells whether senders dequeue too, which they do to make room
+// with OVERFLOW_DROP_OLDEST
+func (actor *innerActor) dropsOldest() bool {
+	return actor.capacity > 0 && actor.overflow == OVERFLOW_DROP_OLDEST
+}
+
+func (actor *innerActor) release() {
+	atomic.AddInt32(&actor.size, -1)
+	if actor.capacity > 0 && actor.overflow == OVERFLOW_BLOCK {
+		actor.mailboxLock.Lock()
+		actor.notFull.Broadcast()
+		actor.mailboxLock.Unlock()
+	}
+}
+
+func (actor *innerActor) close() {
+	atomic.StoreInt32(&actor.closed, 1)
+	if actor.notFull != nil {
+		actor.mailboxLock.Lock()
+		actor.notFull.Broadcast()
+		actor.mailboxLock.Unlock()
+	}
+}
+
+type mailboxQueue interface {
+	Enqueue(value interface{})
+	Dequeue() (interface{}, bool)
+}
+
+// evictingQueue is a mailboxQueue whose next event to drop is not the next
+// one to handle, like the lowest priority of a priority mailbox
+type evictingQueue interface {
+	Evict() (interface{}, bool)
+}
+
+// eventLane holds system messages and stashed events, which are few, so a
+// locked slice will do
+type eventLane struct {
+	lock   sync.Mutex
+	events []*Event
+}
+
+func (lane *eventLane) push(event *Event) {
+	lane.lock.Lock()
+	lane.events = append(lane.events, event)
+	lane.lock.Unlock()
+}
+
+func (lane *eventLane) pop() (*Event, bool) {
+	lane.lock.Lock()
+	defer lane.lock.Unlock()
+	if len(lane.events) == 0 {
+		return nil, false
+	}
+	event := lane.events[0]
+	lane.events[0] = nil
+	lane.events = lane.events[1:]
+	return event, true
+}
+
+func (lane *eventLane) empty() bool {
+	lane.lock.Lock()
+	defer lane.lock.Unlock()
+	return len(lane.events) == 0
+}
+
+func (lane *eventLane) take() []*Event {
+	lane.lock.Lock()
+	defer lane.lock.Unlock()
+	events := lane.events
+	lane.events = nil
+	return events
+}
+
+// isSystemMessage tells the events overtaking user ones: exits and watch
+// notifications. Restarts and hot swaps are applied before every pop anyway.
+func isSystemMessage(event interface{}) bool {
+	switch event.(type) {
+	case ExitEvent, *Terminated:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package goactor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type gateActor struct {
+	gate     chan struct{}
+	received chan interface{}
+}
+
+func newGateActor() *gateActor {
+	return &gateActor{make(chan struct{}), make(chan interface{}, 100)}
+}
+
+func (actor *gateActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *gateActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	<-actor.gate
+	actor.received <- event
+	return event
+}
+
+func (actor *gateActor) OnPullout(system *ActorSystem) {}
+
+type recordDeadLetterProcessor struct {
+	events chan interface{}
+}
+
+func (processor *recordDeadLetterProcessor) Process(actorName string, event interface{}) {
+	processor.events <- event
+}
+
+// fillMailbox leaves the actor stuck on event 0 with events 1..capacity queued
+func fillMailbox(t *testing.T, system *ActorSystem, capacity int) {
+	for i := 0; i <= capacity; i++ {
+		if err := system.Request("bounded", i); err != nil {
+			t.Fatalf("unexpected error on event %d: %v", i, err)
+		}
+		if i == 0 {
+			// a moment for the actor to take event 0 out of the mailbox
+			time.Sleep(time.Duration(10) * time.Millisecond)
+		}
+	}
+}
+
+func collect(actor *gateActor, count int) []interface{} {
+	received := make([]interface{}, count)
+	for i := range received {
+		actor.gate <- struct{}{}
+		received[i] = <-actor.received
+	}
+	return received
+}
+
+func TestMailboxDropNewest(t *testing.T) {
+	system := NewDefaultActorSystem()
+	actor := newGateActor()
+	system.AddActor("bounded", actor, WithMailbox(2, OVERFLOW_DROP_NEWEST))
+	defer system.Shutdown()
+
+	fillMailbox(t, system, 2)
+	if err := system.Request("bounded", 3); err != ErrMailboxFull {
+		t.Errorf("expect mailbox full, got %v", err)
+	}
+	if _, err := system.Require("bounded", 4, 100); err != ErrMailboxFull {
+		t.Errorf("expect mailbox full on require, got %v", err)
+	}
+
+	if received := collect(actor, 3); received[2] != 2 {
+		t.Errorf("newest event not dropped, got %v", received)
+	}
+}
+
+func TestMailboxDropOldest(t *testing.T) {
+	system := NewDefaultActorSystem()
+	processor := &recordDeadLetterProcessor{make(chan interface{}, 10)}
+	system.deadLetterProcessor = processor
+	actor := newGateActor()
+	system.AddActor("bounded", actor, WithMailbox(2, OVERFLOW_DROP_OLDEST))
+	defer system.Shutdown()
+
+	fillMailbox(t, system, 2)
+	if err := system.Request("bounded", 3); err != nil {
+		t.Errorf("drop oldest shouldn't fail sender, got %v", err)
+	}
+	if event := <-processor.events; event != 1 {
+		t.Errorf("expect dropped event 1 in dead letter, got %v", event)
+	}
+
+	if received := collect(actor, 3); received[1] != 2 || received[2] != 3 {
+		t.Errorf("oldest event not dropped, got %v", received)
+	}
+}
+
+func TestMailboxDeadLetter(t *testing.T) {
+	system := NewDefaultActorSystem()
+	processor := &recordDeadLetterProcessor{make(chan interface{}, 10)}
+	system.deadLetterProcessor = processor
+	actor := newGateActor()
+	system.AddActor("bounded", actor, WithMailbox(2, OVERFLOW_DEAD_LETTER))
+	defer system.Shutdown()
+
+	fillMailbox(t, system, 2)
+	if err := system.Request("bounded", 3); err != ErrMailboxFull {
+		t.Errorf("expect mailbox full, got %v", err)
+	}
+	if event := <-processor.events; event != 3 {
+		t.Errorf("expect event 3 in dead letter, got %v", event)
+	}
+	collect(actor, 3)
+}
+
+func TestMailboxBlock(t *testing.T) {
+	system := NewDefaultActorSystem()
+	actor := newGateActor()
+	system.AddActor("bounded", actor, WithMailbox(2, OVERFLOW_BLOCK))
+	defer system.Shutdown()
+
+	fillMailbox(t, system, 2)
+	sent := make(chan error)
+	go func() {
+		sent <- system.Request("bounded", 3)
+	}()
+
+	select {
+	case <-sent:
+		t.Fatal("sender not blocked by full mailbox")
+	case <-time.After(time.Duration(20) * time.Millisecond):
+	}
+
+	actor.gate <- struct{}{}
+	if err := <-sent; err != nil {
+		t.Errorf("blocked sender failed: %v", err)
+	}
+	if received := append([]interface{}{<-actor.received}, collect(actor, 3)...); received[3] != 3 {
+		t.Errorf("blocked event lost, got %v", received)
+	}
+}
+
+func TestMailboxBlockCancelled(t *testing.T) {
+	system := NewDefaultActorSystem()
+	actor := newGateActor()
+	system.AddActor("bounded", actor, WithMailbox(2, OVERFLOW_BLOCK))
+	defer system.Shutdown()
+
+	fillMailbox(t, system, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	sent := make(chan error)
+	go func() {
+		sent <- system.RequestCtx(ctx, "bounded", 3)
+	}()
+
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	cancel()
+	select {
+	case err := <-sent:
+		if err != context.Canceled {
+			t.Errorf("expect cancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked sender not released by its ctx")
+	}
+
+	actor.gate <- struct{}{}
+	if received := append([]interface{}{<-actor.received}, collect(actor, 2)...); len(received) != 3 || received[2] != 2 {
+		t.Errorf("unexpected events %v", received)
+	}
+}
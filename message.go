@@ -0,0 +1,130 @@
+package goactor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrAlreadyReplied = errors.New("message already replied")
+	ErrNoSender       = errors.New("message has no sender to reply")
+)
+
+var correlationSeq uint64
+
+// MessageActorInterface is picked over Receive when implemented. Nothing is
+// answered automatically, a require is answered by Reply or Forward, which
+// may happen later while handling another message.
+type MessageActorInterface interface {
+	ActorInterface
+	ReceiveMessage(msg *Message)
+}
+
+type Message struct {
+	System        *ActorSystem
+	EventType     EventType
+	Event         interface{}
+	Self          string
+	Sender        string
+	CorrelationID uint64
+
+	event *Event
+	actor *innerActor
+}
+
+func (actor *innerActor) message(event *Event) *Message {
+	eventType := EVENT_REQUEST
+	if event.responseChan != nil {
+		eventType = EVENT_REQUIRE
+	}
+
+	return &Message{
+		System:        actor.system,
+		EventType:     eventType,
+		Event:         event.event,
+		Self:          actor.name,
+		Sender:        event.sender,
+		CorrelationID: event.correlationID,
+		event:         event,
+		actor:         actor,
+	}
+}
+
+func (msg *Message) Context() context.Context {
+	ctx := msg.event.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return withCallers(ctx, msg.actor.callers(msg.event))
+}
+
+// Reply answers the require caller, or requests the sender by name when
+// the message was a request
+func (msg *Message) Reply(response interface{}) error {
+	if msg.event.responseChan != nil {
+		if !msg.System.reply(msg.Self, msg.event, response) {
+			return ErrAlreadyReplied
+		}
+		return nil
+	}
+
+	if msg.Sender == "" {
+		return ErrNoSender
+	}
+	return msg.System.send(msg.Sender, &Event{
+		event:         response,
+		sender:        msg.Self,
+		correlationID: msg.CorrelationID,
+	})
+}
+
+// Forward hands the message to another actor, which then replies to the
+// original caller
+func (msg *Message) Forward(actorName string) error {
+	if msg.event.responseChan != nil && atomic.LoadInt32(msg.event.state()) == replySent {
+		return ErrAlreadyReplied
+	}
+
+	forwarded := &Event{
+		event:         msg.Event,
+		responseChan:  msg.event.responseChan,
+		ctx:           msg.event.ctx,
+		sender:        msg.Sender,
+		correlationID: msg.CorrelationID,
+		sentAt:        msg.event.sentAt,
+		timeout:       msg.event.timeout,
+		origin:        msg.event,
+		chain:         msg.event.chain,
+	}
+	err := msg.System.send(actorName, forwarded)
+	if err != nil {
+		forwarded.respond(&failedResponse{err})
+	}
+	return err
+}
+
+func (msg *Message) Request(actorName string, event interface{}) error {
+	return msg.System.send(actorName, &Event{
+		event:         event,
+		sender:        msg.Self,
+		correlationID: msg.CorrelationID,
+	})
+}
+
+func (msg *Message) Require(actorName string, event interface{}, timeoutInMilliSec int) (interface{}, error) {
+	ch := make(chan interface{}, 1)
+	typedEvent := &Event{
+		event:         event,
+		responseChan:  ch,
+		sender:        msg.Self,
+		correlationID: msg.CorrelationID,
+		timeout:       time.Duration(timeoutInMilliSec) * time.Millisecond,
+		chain:         msg.actor.callers(msg.event),
+	}
+	if err := msg.System.send(actorName, typedEvent); err != nil {
+		return nil, err
+	}
+	return msg.System.awaitResponse(actorName, typedEvent, ch, timeoutInMilliSec)
+}
@@ -0,0 +1,125 @@
+package goactor
+
+import (
+	"testing"
+	"time"
+)
+
+type batchActor struct {
+	pending []*Message
+}
+
+func (actor *batchActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *batchActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *batchActor) ReceiveMessage(msg *Message) {
+	if msg.Event != "flush" {
+		actor.pending = append(actor.pending, msg)
+		return
+	}
+	for _, pending := range actor.pending {
+		pending.Reply(len(actor.pending))
+	}
+	actor.pending = nil
+}
+
+func (actor *batchActor) OnPullout(system *ActorSystem) {}
+
+type frontActor struct{}
+
+func (actor *frontActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *frontActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *frontActor) ReceiveMessage(msg *Message) {
+	msg.Forward("back")
+}
+
+func (actor *frontActor) OnPullout(system *ActorSystem) {}
+
+type backActor struct{}
+
+func (actor *backActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *backActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *backActor) ReceiveMessage(msg *Message) {
+	switch msg.Event {
+	case "pong":
+		msg.Reply("pong reply")
+	default:
+		msg.Reply(msg.Sender)
+	}
+}
+
+func (actor *backActor) OnPullout(system *ActorSystem) {}
+
+func TestMessageDeferredReply(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("batch", &batchActor{})
+	defer system.Shutdown()
+
+	first := system.RequireAsync("batch", 1, 1000)
+	second := system.RequireAsync("batch", 2, 1000)
+	system.Request("batch", "flush")
+
+	if rst, err := All(first, second).Await(); err != nil || rst.([]interface{})[0] != 2 || rst.([]interface{})[1] != 2 {
+		t.Errorf("deferred replies not delivered, got %v, %v", rst, err)
+	}
+}
+
+func TestMessageForward(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("front", &frontActor{})
+	system.AddActor("back", &backActor{})
+	defer system.Shutdown()
+
+	if rst, err := system.Require("front", "forward", 1000); rst != "" || err != nil {
+		t.Errorf("forwarded require not replied by back, got %v, %v", rst, err)
+	}
+}
+
+type senderRecorder struct {
+	messages chan *Message
+}
+
+func (actor *senderRecorder) OnPlugin(system *ActorSystem) {}
+
+func (actor *senderRecorder) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *senderRecorder) ReceiveMessage(msg *Message) {
+	if msg.Event == "start" {
+		msg.Request("back", "pong")
+	} else {
+		actor.messages <- msg
+	}
+}
+
+func (actor *senderRecorder) OnPullout(system *ActorSystem) {}
+
+func TestMessageReplyToSender(t *testing.T) {
+	system := NewDefaultActorSystem()
+	recorder := &senderRecorder{make(chan *Message, 1)}
+	system.AddActor("recorder", recorder)
+	system.AddActor("back", &backActor{})
+	defer system.Shutdown()
+
+	system.Request("recorder", "start")
+	select {
+	case msg := <-recorder.messages:
+		if msg.Event != "pong reply" || msg.Sender != "back" || msg.CorrelationID == 0 {
+			t.Errorf("unexpected reply %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("reply never reached sender")
+	}
+}
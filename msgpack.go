@@ -0,0 +1,642 @@
+package goactor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// MsgPackSerializer implements the MessagePack format over reflection.
+// Structs are written as maps keyed by field name, or by their `msgpack`
+// tag, "-" skipping a field.
+type MsgPackSerializer struct {
+}
+
+func (serializer *MsgPackSerializer) Name() string {
+	return "msgpack"
+}
+
+func (serializer *MsgPackSerializer) Marshal(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := msgpackEncode(&buffer, reflect.ValueOf(value)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (serializer *MsgPackSerializer) Unmarshal(data []byte, typ reflect.Type) (interface{}, error) {
+	decoder := &msgpackDecoder{data: data}
+	raw, err := decoder.decode()
+	if err != nil {
+		return nil, err
+	}
+	if decoder.pos != len(data) {
+		return nil, errors.New(fmt.Sprintf("msgpack: %d trailing bytes", len(data)-decoder.pos))
+	}
+
+	if typ == nil {
+		return msgpackGeneric(raw)
+	}
+	target := reflect.New(typ).Elem()
+	if err := msgpackAssign(target, raw); err != nil {
+		return nil, err
+	}
+	return target.Interface(), nil
+}
+
+func msgpackEncode(buffer *bytes.Buffer, value reflect.Value) error {
+	if !value.IsValid() {
+		buffer.WriteByte(0xc0)
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			buffer.WriteByte(0xc0)
+			return nil
+		}
+		return msgpackEncode(buffer, value.Elem())
+	case reflect.Bool:
+		if value.Bool() {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		msgpackInt(buffer, value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		msgpackUint(buffer, value.Uint())
+	case reflect.Float32:
+		buffer.WriteByte(0xca)
+		binary.Write(buffer, binary.BigEndian, math.Float32bits(float32(value.Float())))
+	case reflect.Float64:
+		buffer.WriteByte(0xcb)
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(value.Float()))
+	case reflect.String:
+		msgpackString(buffer, value.String())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			buffer.WriteByte(0xc0)
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, value.Len())
+			reflect.Copy(reflect.ValueOf(data), value)
+			msgpackHeader(buffer, len(data), 0xff, 0xc4, 0xc5, 0xc6)
+			buffer.Write(data)
+			return nil
+		}
+		msgpackHeader(buffer, value.Len(), 0x90, 0, 0xdc, 0xdd)
+		for i := 0; i < value.Len(); i++ {
+			if err := msgpackEncode(buffer, value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			buffer.WriteByte(0xc0)
+			return nil
+		}
+		msgpackHeader(buffer, value.Len(), 0x80, 0, 0xde, 0xdf)
+		for _, key := range value.MapKeys() {
+			if err := msgpackEncode(buffer, key); err != nil {
+				return err
+			}
+			if err := msgpackEncode(buffer, value.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := msgpackFields(value.Type())
+		msgpackHeader(buffer, len(fields), 0x80, 0, 0xde, 0xdf)
+		for _, field := range fields {
+			msgpackString(buffer, field.name)
+			if err := msgpackEncode(buffer, value.Field(field.index)); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New(fmt.Sprintf("msgpack: unsupported type %v", value.Type()))
+	}
+	return nil
+}
+
+// msgpackHeader writes a length header, fixed is the fix format or 0xff if
+// there is none and short the 8 bits format or 0 if there is none
+func msgpackHeader(buffer *bytes.Buffer, length int, fixed byte, short byte, medium byte, long byte) {
+	switch {
+	case fixed != 0xff && length < 16:
+		buffer.WriteByte(fixed | byte(length))
+	case short != 0 && length <= math.MaxUint8:
+		buffer.WriteByte(short)
+		buffer.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buffer.WriteByte(medium)
+		binary.Write(buffer, binary.BigEndian, uint16(length))
+	default:
+		buffer.WriteByte(long)
+		binary.Write(buffer, binary.BigEndian, uint32(length))
+	}
+}
+
+func msgpackString(buffer *bytes.Buffer, s string) {
+	if len(s) < 32 {
+		buffer.WriteByte(0xa0 | byte(len(s)))
+	} else {
+		msgpackHeader(buffer, len(s), 0xff, 0xd9, 0xda, 0xdb)
+	}
+	buffer.WriteString(s)
+}
+
+func msgpackInt(buffer *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0:
+		msgpackUint(buffer, uint64(i))
+	case i >= -32:
+		buffer.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		buffer.WriteByte(0xd0)
+		buffer.WriteByte(byte(i))
+	case i >= math.MinInt16:
+		buffer.WriteByte(0xd1)
+		binary.Write(buffer, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		buffer.WriteByte(0xd2)
+		binary.Write(buffer, binary.BigEndian, int32(i))
+	default:
+		buffer.WriteByte(0xd3)
+		binary.Write(buffer, binary.BigEndian, i)
+	}
+}
+
+func msgpackUint(buffer *bytes.Buffer, u uint64) {
+	switch {
+	case u < 128:
+		buffer.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		buffer.WriteByte(0xcc)
+		buffer.WriteByte(byte(u))
+	case u <= math.MaxUint16:
+		buffer.WriteByte(0xcd)
+		binary.Write(buffer, binary.BigEndian, uint16(u))
+	case u <= math.MaxUint32:
+		buffer.WriteByte(0xce)
+		binary.Write(buffer, binary.BigEndian, uint32(u))
+	default:
+		buffer.WriteByte(0xcf)
+		binary.Write(buffer, binary.BigEndian, u)
+	}
+}
+
+type msgpackField struct {
+	name  string
+	index int
+}
+
+func msgpackFields(typ reflect.Type) []msgpackField {
+	var fields []msgpackField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("msgpack"); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = strings.Split(tag, ",")[0]
+		}
+		fields = append(fields, msgpackField{name, i})
+	}
+	return fields
+}
+
+// msgpackPair keeps map entries in order and with keys of any type, until
+// they get assigned
+type msgpackPair struct {
+	key   interface{}
+	value interface{}
+}
+
+// msgpackMaxDepth bounds the nesting of arrays and maps, data from the wire
+// must not recurse the decoder out of its stack
+const msgpackMaxDepth = 256
+
+type msgpackDecoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+var (
+	errMsgpackShort = errors.New("msgpack: unexpected end of data")
+	errMsgpackDeep  = errors.New(fmt.Sprintf("msgpack: nested deeper than %d", msgpackMaxDepth))
+)
+
+func (decoder *msgpackDecoder) enter() error {
+	decoder.depth++
+	if decoder.depth > msgpackMaxDepth {
+		return errMsgpackDeep
+	}
+	return nil
+}
+
+func (decoder *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || decoder.pos+n > len(decoder.data) {
+		return nil, errMsgpackShort
+	}
+	data := decoder.data[decoder.pos : decoder.pos+n]
+	decoder.pos += n
+	return data, nil
+}
+
+func (decoder *msgpackDecoder) length(size int) (int, error) {
+	data, err := decoder.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(data[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(data)), nil
+	default:
+		return int(binary.BigEndian.Uint32(data)), nil
+	}
+}
+
+// decode reads one value as nil, bool, int64, uint64, float32, float64,
+// string, []byte, []interface{} or []msgpackPair
+func (decoder *msgpackDecoder) decode() (interface{}, error) {
+	head, err := decoder.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	b := head[0]
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b >= 0xa0 && b <= 0xbf:
+		return decoder.str(int(b & 0x1f))
+	case b >= 0x90 && b <= 0x9f:
+		return decoder.array(int(b & 0x0f))
+	case b >= 0x80 && b <= 0x8f:
+		return decoder.pairs(int(b & 0x0f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := decoder.length(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := decoder.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), data...), nil
+	case 0xca:
+		data, err := decoder.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(data)), nil
+	case 0xcb:
+		data, err := decoder.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		data, err := decoder.next(1 << (b - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, d := range data {
+			u = u<<8 | uint64(d)
+		}
+		return u, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		data, err := decoder.next(1 << (b - 0xd0))
+		if err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, d := range data {
+			u = u<<8 | uint64(d)
+		}
+		// sign extend from the width read
+		shift := uint(64 - 8*len(data))
+		return int64(u<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := decoder.length(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.str(n)
+	case 0xdc, 0xdd:
+		n, err := decoder.length(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.array(n)
+	case 0xde, 0xdf:
+		n, err := decoder.length(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.pairs(n)
+	}
+	return nil, errors.New(fmt.Sprintf("msgpack: unsupported format 0x%x", b))
+}
+
+func (decoder *msgpackDecoder) str(n int) (interface{}, error) {
+	data, err := decoder.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (decoder *msgpackDecoder) array(n int) (interface{}, error) {
+	if n > len(decoder.data)-decoder.pos {
+		return nil, errMsgpackShort
+	}
+	if err := decoder.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { decoder.depth-- }()
+	array := make([]interface{}, n)
+	for i := range array {
+		value, err := decoder.decode()
+		if err != nil {
+			return nil, err
+		}
+		array[i] = value
+	}
+	return array, nil
+}
+
+func (decoder *msgpackDecoder) pairs(n int) (interface{}, error) {
+	if n > len(decoder.data)-decoder.pos {
+		return nil, errMsgpackShort
+	}
+	if err := decoder.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { decoder.depth-- }()
+	pairs := make([]msgpackPair, n)
+	for i := range pairs {
+		key, err := decoder.decode()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decoder.decode()
+		if err != nil {
+			return nil, err
+		}
+		pairs[i] = msgpackPair{key, value}
+	}
+	return pairs, nil
+}
+
+// msgpackGeneric turns decoded maps into map[string]interface{}, or
+// map[interface{}]interface{} if a key isn't a string. Keys decoded as
+// arrays, maps or bin can't be hashed and fail.
+func msgpackGeneric(raw interface{}) (interface{}, error) {
+	switch value := raw.(type) {
+	case []interface{}:
+		for i := range value {
+			item, err := msgpackGeneric(value[i])
+			if err != nil {
+				return nil, err
+			}
+			value[i] = item
+		}
+		return value, nil
+	case []msgpackPair:
+		byString := make(map[string]interface{}, len(value))
+		for _, pair := range value {
+			key, ok := pair.key.(string)
+			if !ok {
+				return msgpackAnyMap(value)
+			}
+			item, err := msgpackGeneric(pair.value)
+			if err != nil {
+				return nil, err
+			}
+			byString[key] = item
+		}
+		return byString, nil
+	}
+	return raw, nil
+}
+
+func msgpackAnyMap(pairs []msgpackPair) (interface{}, error) {
+	byAny := make(map[interface{}]interface{}, len(pairs))
+	for _, pair := range pairs {
+		if !msgpackHashable(pair.key) {
+			return nil, errors.New(fmt.Sprintf("msgpack: map key of type %T can't be hashed", pair.key))
+		}
+		item, err := msgpackGeneric(pair.value)
+		if err != nil {
+			return nil, err
+		}
+		byAny[pair.key] = item
+	}
+	return byAny, nil
+}
+
+// msgpackHashable tells whether a decoded value can be a key of a Go map
+func msgpackHashable(raw interface{}) bool {
+	switch raw.(type) {
+	case []interface{}, []msgpackPair, []byte:
+		return false
+	}
+	return true
+}
+
+func msgpackAssign(target reflect.Value, raw interface{}) error {
+	if raw == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	mismatch := errors.New(fmt.Sprintf("msgpack: cannot decode %T into %v", raw, target.Type()))
+	switch target.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(target.Type().Elem())
+		if err := msgpackAssign(elem.Elem(), raw); err != nil {
+			return err
+		}
+		target.Set(elem)
+	case reflect.Interface:
+		generic, err := msgpackGeneric(raw)
+		if err != nil {
+			return err
+		}
+		value := reflect.ValueOf(generic)
+		if !value.Type().AssignableTo(target.Type()) {
+			return mismatch
+		}
+		target.Set(value)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return mismatch
+		}
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch n := raw.(type) {
+		case int64:
+			i = n
+		case uint64:
+			if n > math.MaxInt64 {
+				return mismatch
+			}
+			i = int64(n)
+		default:
+			return mismatch
+		}
+		if target.OverflowInt(i) {
+			return mismatch
+		}
+		target.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch n := raw.(type) {
+		case uint64:
+			u = n
+		case int64:
+			if n < 0 {
+				return mismatch
+			}
+			u = uint64(n)
+		default:
+			return mismatch
+		}
+		if target.OverflowUint(u) {
+			return mismatch
+		}
+		target.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		switch n := raw.(type) {
+		case float64:
+			target.SetFloat(n)
+		case float32:
+			target.SetFloat(float64(n))
+		case int64:
+			target.SetFloat(float64(n))
+		case uint64:
+			target.SetFloat(float64(n))
+		default:
+			return mismatch
+		}
+	case reflect.String:
+		switch s := raw.(type) {
+		case string:
+			target.SetString(s)
+		case []byte:
+			target.SetString(string(s))
+		default:
+			return mismatch
+		}
+	case reflect.Slice:
+		if target.Type().Elem().Kind() == reflect.Uint8 {
+			switch data := raw.(type) {
+			case []byte:
+				target.SetBytes(data)
+				return nil
+			case string:
+				target.SetBytes([]byte(data))
+				return nil
+			}
+		}
+		array, ok := raw.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		slice := reflect.MakeSlice(target.Type(), len(array), len(array))
+		for i, item := range array {
+			if err := msgpackAssign(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+	case reflect.Array:
+		if data, ok := raw.([]byte); ok && target.Type().Elem().Kind() == reflect.Uint8 && len(data) == target.Len() {
+			reflect.Copy(target, reflect.ValueOf(data))
+			return nil
+		}
+		array, ok := raw.([]interface{})
+		if !ok || len(array) != target.Len() {
+			return mismatch
+		}
+		for i, item := range array {
+			if err := msgpackAssign(target.Index(i), item); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		pairs, ok := raw.([]msgpackPair)
+		if !ok {
+			return mismatch
+		}
+		m := reflect.MakeMapWithSize(target.Type(), len(pairs))
+		for _, pair := range pairs {
+			if target.Type().Key().Kind() == reflect.Interface && !msgpackHashable(pair.key) {
+				return mismatch
+			}
+			key := reflect.New(target.Type().Key()).Elem()
+			if err := msgpackAssign(key, pair.key); err != nil {
+				return err
+			}
+			value := reflect.New(target.Type().Elem()).Elem()
+			if err := msgpackAssign(value, pair.value); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		target.Set(m)
+	case reflect.Struct:
+		pairs, ok := raw.([]msgpackPair)
+		if !ok {
+			return mismatch
+		}
+		fields := make(map[string]int)
+		for _, field := range msgpackFields(target.Type()) {
+			fields[field.name] = field.index
+		}
+		for _, pair := range pairs {
+			name, ok := pair.key.(string)
+			if !ok {
+				return mismatch
+			}
+			// unknown fields are skipped, as encoding/json does
+			if index, ok := fields[name]; ok {
+				if err := msgpackAssign(target.Field(index), pair.value); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return mismatch
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package goactor
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var ErrRequireAbandoned = errors.New("require caller has given up")
+
+// OrphanedResponseError carries a reply which arrived after its require
+// timed out or got cancelled
+type OrphanedResponseError struct {
+	ActorName string
+	Response  interface{}
+	Elapsed   time.Duration
+}
+
+func (err *OrphanedResponseError) Error() string {
+	return fmt.Sprintf("actor %s responded \"%+v\" after %v, caller has given up", err.ActorName, err.Response, err.Elapsed)
+}
+
+// abandon marks a require as given up by its caller, false if the response
+// has already been sent
+func (system *ActorSystem) abandon(event *Event) bool {
+	if !atomic.CompareAndSwapInt32(event.state(), replyPending, replyAbandoned) {
+		return false
+	}
+	atomic.AddUint64(&system.abandoned, 1)
+	return true
+}
+
+// reply answers event, a response nobody waits for any more becomes a dead
+// letter. It is false only if event has been replied before.
+func (system *ActorSystem) reply(actorName string, event *Event, response interface{}) bool {
+	if event.respond(response) {
+		return true
+	}
+	if event.responseChan == nil || atomic.LoadInt32(event.state()) != replyAbandoned {
+		return false
+	}
+
+	if _, ok := response.(*failedResponse); !ok {
+		atomic.AddUint64(&system.orphaned, 1)
+		system.deadLetter(actorName, event, DEAD_LETTER_ORPHANED, &OrphanedResponseError{
+			ActorName: actorName,
+			Response:  response,
+			Elapsed:   time.Since(event.sentAt),
+		})
+	}
+	return true
+}
+
+func (system *ActorSystem) skipping(event *Event) bool {
+	return system != nil && event.responseChan != nil &&
+		atomic.LoadInt32(&system.skipAbandoned) != 0 &&
+		atomic.LoadInt32(event.state()) == replyAbandoned
+}
+
+// SkipAbandoned makes actors drop requires whose caller has already timed
+// out, instead of processing them for nobody
+func (system *ActorSystem) SkipAbandoned(skip bool) {
+	if skip {
+		atomic.StoreInt32(&system.skipAbandoned, 1)
+	} else {
+		atomic.StoreInt32(&system.skipAbandoned, 0)
+	}
+}
+
+// AbandonedRequires counts requires given up on timeout or cancellation
+func (system *ActorSystem) AbandonedRequires() uint64 {
+	return atomic.LoadUint64(&system.abandoned)
+}
+
+// OrphanedResponses counts responses which arrived after the caller gave up
+func (system *ActorSystem) OrphanedResponses() uint64 {
+	return atomic.LoadUint64(&system.orphaned)
+}
@@ -0,0 +1,77 @@
+package goactor
+
+import (
+	"testing"
+	"time"
+)
+
+func waitLetters(store *DeadLetterStore, count int) []*DeadLetter {
+	for i := 0; i < 100; i++ {
+		if letters := store.List(); len(letters) >= count {
+			return letters
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return store.List()
+}
+
+func TestOrphanedResponse(t *testing.T) {
+	store := NewDeadLetterStore(10)
+	system := NewDefaultActorSystem()
+	system.SetDeadLetterProcessor(store)
+	actor := newGateActor()
+	system.AddActor("slow", actor)
+	defer system.Shutdown()
+
+	if _, err := system.Require("slow", "late", 20); err == nil {
+		t.Fatal("expect timeout")
+	}
+	close(actor.gate)
+
+	letters := waitLetters(store, 1)
+	if len(letters) != 1 {
+		t.Fatalf("expect 1 orphaned response, got %+v", letters)
+	}
+	letter := letters[0]
+	if letter.Reason != DEAD_LETTER_ORPHANED || letter.Event != "late" || letter.Response != "late" || letter.ActorName != "slow" {
+		t.Errorf("unexpected letter %+v", letter)
+	}
+	if letter.Elapsed < 20*time.Millisecond {
+		t.Errorf("expect elapsed over the timeout, got %v", letter.Elapsed)
+	}
+	if system.AbandonedRequires() != 1 || system.OrphanedResponses() != 1 {
+		t.Errorf("unexpected counters %d %d", system.AbandonedRequires(), system.OrphanedResponses())
+	}
+}
+
+func TestSkipAbandoned(t *testing.T) {
+	store := NewDeadLetterStore(10)
+	system := NewDefaultActorSystem()
+	system.SetDeadLetterProcessor(store)
+	system.SkipAbandoned(true)
+	actor := newGateActor()
+	system.AddActor("slow", actor)
+	defer system.Shutdown()
+
+	system.Request("slow", "first")
+	if _, err := system.Require("slow", "skipped", 20); err == nil {
+		t.Fatal("expect timeout")
+	}
+	close(actor.gate)
+
+	letters := waitLetters(store, 1)
+	if len(letters) != 1 || letters[0].Reason != DEAD_LETTER_TIMEOUT || letters[0].Event != "skipped" {
+		t.Fatalf("unexpected letters %+v", letters)
+	}
+	if event := <-actor.received; event != "first" {
+		t.Errorf("unexpected event %v", event)
+	}
+	select {
+	case event := <-actor.received:
+		t.Errorf("abandoned require processed: %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if system.OrphanedResponses() != 0 {
+		t.Errorf("expect no orphaned response, got %d", system.OrphanedResponses())
+	}
+}
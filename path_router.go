@@ -0,0 +1,137 @@
+package goactor
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// PathRouter treats actor names as slash separated paths, like
+// "payments/eu". A name to route is resolved by precedence:
+//  1. the exact registered name
+//  2. for patterns, the smallest matching registered name in lexical order.
+//     A segment is matched by path.Match, a trailing "**" matches any depth.
+//  3. the longest registered prefix, "payments/eu/de" falls back to
+//     "payments/eu" then "payments"
+type PathRouter struct {
+	balancer Balancer
+}
+
+func NewPathRouter(balancer Balancer) *PathRouter {
+	return &PathRouter{
+		balancer: balancer,
+	}
+}
+
+func NewPathWithRandomBalancerRouter() *PathRouter {
+	return NewPathRouter(NewRandomBalancer())
+}
+
+func (router *PathRouter) Route(actorName string, actors map[string][]*innerActor) (actor *innerActor, err error) {
+	return router.RouteEvent(actorName, nil, actors)
+}
+
+func (router *PathRouter) RouteEvent(actorName string, event interface{}, actors map[string][]*innerActor) (actor *innerActor, err error) {
+	if name, ok := router.resolve(actorName, actors); ok {
+		return choose(router.balancer, name, event, actors[name]), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unable to find actor match %s", actorName))
+}
+
+// RouteRegistry goes through every name for patterns only
+func (router *PathRouter) RouteRegistry(actorName string, event interface{}, registry *actorRegistry) (actor *innerActor, err error) {
+	if actors := registry.lookup(actorName); len(actors) > 0 {
+		return choose(router.balancer, actorName, event, actors), nil
+	}
+	if isPathPattern(actorName) {
+		return router.RouteEvent(actorName, event, registry.all())
+	}
+
+	for name := actorName; ; {
+		index := strings.LastIndex(name, "/")
+		if index < 0 {
+			return nil, errors.New(fmt.Sprintf("Unable to find actor match %s", actorName))
+		}
+		name = name[:index]
+		if actors := registry.lookup(name); len(actors) > 0 {
+			return choose(router.balancer, name, event, actors), nil
+		}
+	}
+}
+
+func (router *PathRouter) forget(actorName string) {
+	forget(router.balancer, actorName)
+}
+
+func (router *PathRouter) RouteAll(pattern string, event interface{}, actors map[string][]*innerActor) (matched []*innerActor, err error) {
+	names := matchNames(pattern, actors)
+	if len(names) == 0 {
+		return nil, errors.New(fmt.Sprintf("Unable to find actor match %s", pattern))
+	}
+
+	matched = make([]*innerActor, len(names))
+	for i, name := range names {
+		matched[i] = choose(router.balancer, name, event, actors[name])
+	}
+	return matched, nil
+}
+
+func (router *PathRouter) resolve(actorName string, actors map[string][]*innerActor) (string, bool) {
+	if _, ok := actors[actorName]; ok {
+		return actorName, true
+	}
+
+	if isPathPattern(actorName) {
+		if names := matchNames(actorName, actors); len(names) > 0 {
+			return names[0], true
+		}
+		return "", false
+	}
+
+	for name := actorName; ; {
+		index := strings.LastIndex(name, "/")
+		if index < 0 {
+			return "", false
+		}
+		name = name[:index]
+		if _, ok := actors[name]; ok {
+			return name, true
+		}
+	}
+}
+
+func isPathPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// matchNames returns sorted registered names matching pattern
+func matchNames(pattern string, actors map[string][]*innerActor) []string {
+	var names []string
+	for name := range actors {
+		if matchPath(pattern, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func matchPath(pattern string, name string) bool {
+	patternSegments := strings.Split(pattern, "/")
+	nameSegments := strings.Split(name, "/")
+
+	for i, segment := range patternSegments {
+		if segment == "**" && i == len(patternSegments)-1 {
+			return true
+		}
+		if i >= len(nameSegments) {
+			return false
+		}
+		if matched, err := path.Match(segment, nameSegments[i]); err != nil || !matched {
+			return false
+		}
+	}
+	return len(patternSegments) == len(nameSegments)
+}
@@ -0,0 +1,101 @@
+package goactor
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Prioritized events are handled before the ones of lower priority by
+// actors with a priority mailbox, events without one are of priority 0
+type Prioritized interface {
+	Priority() int
+}
+
+// WithPriorityMailbox hands out events by priority, with OVERFLOW_DROP_OLDEST
+// the oldest event of the lowest priority is dropped for room
+func WithPriorityMailbox() ActorOption {
+	return func(actor *innerActor) {
+		actor.events = newPriorityQueue()
+	}
+}
+
+type prioritizedEvent struct {
+	value    interface{}
+	priority int
+	seq      uint64
+}
+
+type eventHeap []*prioritizedEvent
+
+func (h eventHeap) Len() int { return len(h) }
+
+func (h eventHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	// first in first out within a priority
+	return h[i].seq < h[j].seq
+}
+
+func (h eventHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *eventHeap) Push(x interface{}) { *h = append(*h, x.(*prioritizedEvent)) }
+
+func (h *eventHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return last
+}
+
+type priorityQueue struct {
+	events eventHeap
+	seq    uint64
+	lock   sync.Mutex
+}
+
+func newPriorityQueue() *priorityQueue {
+	return &priorityQueue{}
+}
+
+func (queue *priorityQueue) Enqueue(value interface{}) {
+	priority := 0
+	if event, ok := value.(*Event); ok {
+		if prioritized, ok := event.event.(Prioritized); ok {
+			priority = prioritized.Priority()
+		}
+	}
+
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	queue.seq++
+	heap.Push(&queue.events, &prioritizedEvent{value, priority, queue.seq})
+}
+
+func (queue *priorityQueue) Dequeue() (interface{}, bool) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	if len(queue.events) == 0 {
+		return nil, false
+	}
+	return heap.Pop(&queue.events).(*prioritizedEvent).value, true
+}
+
+// Evict drops the oldest event of the lowest priority, so that overflowing
+// sheds the least urgent work first
+func (queue *priorityQueue) Evict() (interface{}, bool) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	if len(queue.events) == 0 {
+		return nil, false
+	}
+	lowest := 0
+	for i, event := range queue.events {
+		if event.priority < queue.events[lowest].priority ||
+			event.priority == queue.events[lowest].priority && event.seq < queue.events[lowest].seq {
+			lowest = i
+		}
+	}
+	return heap.Remove(&queue.events, lowest).(*prioritizedEvent).value, true
+}
@@ -0,0 +1,91 @@
+package goactor
+
+import (
+	"testing"
+	"time"
+)
+
+type urgent int
+
+func (event urgent) Priority() int {
+	return int(event)
+}
+
+func TestExitOvertakesMailbox(t *testing.T) {
+	store := NewDeadLetterStore(100)
+	system := NewDefaultActorSystem()
+	system.SetDeadLetterProcessor(store)
+	actor := newGateActor()
+	system.AddActor("backlog", actor)
+	defer system.Shutdown()
+
+	for i := 0; i < 50; i++ {
+		system.Request("backlog", i)
+	}
+	stopped := system.instance("backlog", actor).stopped
+
+	system.RemoveActor("backlog", actor)
+	close(actor.gate)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("actor didn't stop")
+	}
+	if len(actor.received) > 1 {
+		t.Errorf("expect at most the event in process handled, got %d", len(actor.received))
+	}
+
+	letters := store.Filter(func(letter *DeadLetter) bool { return letter.Reason == DEAD_LETTER_STOPPED })
+	if len(letters)+len(actor.received) != 50 {
+		t.Errorf("expect the backlog dead lettered, got %d", len(letters))
+	}
+}
+
+func TestPriorityMailbox(t *testing.T) {
+	system := NewDefaultActorSystem()
+	actor := newGateActor()
+	system.AddActor("prioritized", actor, WithPriorityMailbox())
+	defer system.Shutdown()
+
+	system.Request("prioritized", "blocking")
+	time.Sleep(10 * time.Millisecond)
+	for _, event := range []interface{}{"plain", urgent(1), urgent(5), "later", urgent(-1), urgent(5)} {
+		system.Request("prioritized", event)
+	}
+
+	expect := []interface{}{"blocking", urgent(5), urgent(5), urgent(1), "plain", "later", urgent(-1)}
+	for i, event := range collect(actor, len(expect)) {
+		if event != expect[i] {
+			t.Errorf("expect %v at %d, got %v", expect[i], i, event)
+		}
+	}
+}
+
+func TestPriorityMailboxDropOldest(t *testing.T) {
+	system := NewDefaultActorSystem()
+	processor := &recordDeadLetterProcessor{make(chan interface{}, 10)}
+	system.deadLetterProcessor = processor
+	actor := newGateActor()
+	system.AddActor("prioritized", actor, WithPriorityMailbox(), WithMailbox(3, OVERFLOW_DROP_OLDEST))
+	defer system.Shutdown()
+
+	system.Request("prioritized", "blocking")
+	time.Sleep(10 * time.Millisecond)
+	for _, event := range []interface{}{urgent(5), "plain", urgent(1), urgent(9), "later"} {
+		system.Request("prioritized", event)
+	}
+
+	// the least urgent go first, not the next to handle
+	for _, expect := range []interface{}{"plain", urgent(1)} {
+		if event := <-processor.events; event != expect {
+			t.Errorf("expect %v dropped, got %v", expect, event)
+		}
+	}
+	expect := []interface{}{"blocking", urgent(9), urgent(5), "later"}
+	for i, event := range collect(actor, len(expect)) {
+		if event != expect[i] {
+			t.Errorf("expect %v at %d, got %v", expect[i], i, event)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package goactor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// actorRegistry maps names to instance slices which are never changed once
+// stored. A change replaces the slice of its own name only, so looking a name
+// up takes no lock nor sees a slice being changed, and adding an actor costs
+// the same whatever the number of names.
+type actorRegistry struct {
+	names sync.Map // name -> []*innerActor
+
+	// version counts changes
+	version uint64
+	// view is the whole map routers going through every name ask for, a
+	// map[string][]*innerActor replaced on every change once asked for: until
+	// then changes don't pay for the copy
+	view   atomic.Value
+	wanted int32
+	lock   sync.Mutex
+
+	types *typeIndex
+}
+
+func newActorRegistry() *actorRegistry {
+	return &actorRegistry{types: newTypeIndex()}
+}
+
+func (registry *actorRegistry) lookup(name string) []*innerActor {
+	actors, _ := registry.names.Load(name)
+	instances, _ := actors.([]*innerActor)
+	return instances
+}
+
+// all is the whole registry as a map, for routers going through every name
+func (registry *actorRegistry) all() map[string][]*innerActor {
+	if view, ok := registry.view.Load().(map[string][]*innerActor); ok {
+		return view
+	}
+
+	// the first time only
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if view, ok := registry.view.Load().(map[string][]*innerActor); ok {
+		return view
+	}
+	atomic.StoreInt32(&registry.wanted, 1)
+	actors := make(map[string][]*innerActor)
+	registry.names.Range(func(name, instances interface{}) bool {
+		actors[name.(string)] = instances.([]*innerActor)
+		return true
+	})
+	registry.view.Store(actors)
+	return actors
+}
+
+// changed keeps the view up to date with the instances of name
+func (registry *actorRegistry) changed(name string, actors []*innerActor) {
+	atomic.AddUint64(&registry.version, 1)
+	if atomic.LoadInt32(&registry.wanted) == 0 {
+		return
+	}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	previous, _ := registry.view.Load().(map[string][]*innerActor)
+	view := make(map[string][]*innerActor, len(previous)+1)
+	for other, instances := range previous {
+		view[other] = instances
+	}
+	if len(actors) == 0 {
+		delete(view, name)
+	} else {
+		view[name] = actors
+	}
+	registry.view.Store(view)
+}
+
+// publish requires system.lock held, empty actors drops name
+func (registry *actorRegistry) publish(name string, actors []*innerActor) {
+	registry.types.update(registry.lookup(name), actors)
+	if len(actors) == 0 {
+		registry.names.Delete(name)
+	} else {
+		registry.names.Store(name, actors)
+	}
+	registry.changed(name, actors)
+}
+
+// clear requires system.lock held and returns every instance dropped
+func (registry *actorRegistry) clear() (dropped []*innerActor) {
+	registry.names.Range(func(name, instances interface{}) bool {
+		dropped = append(dropped, instances.([]*innerActor)...)
+		registry.types.update(instances.([]*innerActor), nil)
+		registry.names.Delete(name)
+		return true
+	})
+	atomic.AddUint64(&registry.version, 1)
+	if atomic.LoadInt32(&registry.wanted) != 0 {
+		registry.lock.Lock()
+		registry.view.Store(make(map[string][]*innerActor))
+		registry.lock.Unlock()
+	}
+	return dropped
+}
+
+// reindex requires system.lock held, for actor accepting other types now
+func (registry *actorRegistry) reindex(actor *innerActor) {
+	registry.types.update([]*innerActor{actor}, nil)
+	registry.types.update(nil, []*innerActor{actor})
+	atomic.AddUint64(&registry.version, 1)
+}
+
+// without copies actors but the one at index
+func without(actors []*innerActor, index int) []*innerActor {
+	rest := make([]*innerActor, 0, len(actors)-1)
+	return append(append(rest, actors[:index]...), actors[index+1:]...)
+}
@@ -0,0 +1,697 @@
+package goactor
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrRemoteUnavailable  = errors.New("remote node unavailable")
+	ErrRemoteUnauthorized = errors.New("remote peer failed the handshake")
+	ErrNoRemoteSecret     = errors.New("no remote secret set, see SetRemoteSecret")
+)
+
+// RemoteRequireTimeout bounds a require to a remote actor whose caller gave
+// neither a timeout nor a deadline in its ctx
+var RemoteRequireTimeout = 30 * time.Second
+
+// MaxRemoteFrame bounds the size of a message between systems
+var MaxRemoteFrame uint32 = 4 << 20
+
+const (
+	remoteDialTimeout  = 5 * time.Second
+	maxHandshakeFrame  = 1 << 10
+	defaultMaxRequires = 64
+)
+
+// RemoteError is a failure raised by the remote side of a require
+type RemoteError struct {
+	Node    string
+	Message string
+}
+
+func (err *RemoteError) Error() string {
+	return fmt.Sprintf("remote %s: %s", err.Node, err.Message)
+}
+
+const (
+	envelopeRequest byte = iota
+	envelopeRequire
+	envelopeResponse
+	envelopeHello
+)
+
+type envelope struct {
+	Kind    byte
+	ID      uint64
+	Target  string
+	Sender  string
+	Timeout int
+	Event   *Payload
+	Error   string
+	Proof   []byte
+	Nonce   []byte
+}
+
+// writeEnvelope sends a length prefixed frame, each frame being a gob stream
+// of its own. Events are serialized by the registry of the system beforehand.
+func writeEnvelope(w io.Writer, env *envelope) error {
+	var buffer bytes.Buffer
+	buffer.Write(make([]byte, 4))
+	if err := gob.NewEncoder(&buffer).Encode(env); err != nil {
+		return err
+	}
+
+	frame := buffer.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	_, err := w.Write(frame)
+	return err
+}
+
+func readEnvelope(r io.Reader, limit uint32) (*envelope, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > limit {
+		return nil, errors.New(fmt.Sprintf("frame of %d bytes exceeds the limit", size))
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	env := &envelope{}
+	if err := gob.NewDecoder(bytes.NewReader(frame)).Decode(env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// SetRemoteSecret sets the secret shared by the systems talking to each
+// other. Both ends of a connection prove they know it, with an HMAC of a
+// random challenge of the other end, before any message goes through.
+// Listen refuses to start without one.
+func (system *ActorSystem) SetRemoteSecret(secret string) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	system.remoteSecret = []byte(secret)
+}
+
+func (system *ActorSystem) secret() []byte {
+	system.lock.RLock()
+	defer system.lock.RUnlock()
+	return system.remoteSecret
+}
+
+// proof answers challenge as role, "dial" or "listen", so the proof of one
+// end can't be replayed as one of the other
+func proof(secret []byte, role string, challenge []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(role))
+	mac.Write(challenge)
+	return mac.Sum(nil)
+}
+
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// decode deserializes a payload from a peer, one crashing its serializer
+// fails like an undecodable one
+func (system *ActorSystem) decode(payload *Payload) (event interface{}, err error) {
+	if payload == nil {
+		return nil, errors.New("no payload")
+	}
+	defer func() {
+		if reason := recover(); reason != nil {
+			err = errors.New(fmt.Sprintf("deserializing %s panicked: %v", payload.Manifest, reason))
+		}
+	}()
+	return system.serializerRegistry().Deserialize(payload)
+}
+
+// RemoteServer exposes the actors of a system to other systems
+type RemoteServer struct {
+	system   *ActorSystem
+	listener net.Listener
+
+	exported    map[string]bool
+	maxRequires int
+
+	conns  map[net.Conn]struct{}
+	closed bool
+	lock   sync.Mutex
+}
+
+type ListenOption func(server *RemoteServer)
+
+// WithExported makes the actors of names reachable from remote systems,
+// nothing else is
+func WithExported(names ...string) ListenOption {
+	return func(server *RemoteServer) {
+		for _, name := range names {
+			server.exported[name] = true
+		}
+	}
+}
+
+// WithMaxRequires bounds the requires a connection has in progress, reading
+// further ones waits for one to finish
+func WithMaxRequires(limit int) ListenOption {
+	return func(server *RemoteServer) {
+		server.maxRequires = limit
+	}
+}
+
+// Listen accepts requests and requires from remote systems on address, for
+// the actors exported by name. It fails with ErrNoRemoteSecret until
+// SetRemoteSecret was called, anyone reaching address could call them
+// otherwise.
+func (system *ActorSystem) Listen(address string, options ...ListenOption) (*RemoteServer, error) {
+	if len(system.secret()) == 0 {
+		return nil, ErrNoRemoteSecret
+	}
+	server := &RemoteServer{
+		system:      system,
+		exported:    make(map[string]bool),
+		maxRequires: defaultMaxRequires,
+		conns:       make(map[net.Conn]struct{}),
+	}
+	for _, option := range options {
+		option(server)
+	}
+	if server.maxRequires <= 0 {
+		server.maxRequires = defaultMaxRequires
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	server.listener = listener
+	go server.accept()
+	return server, nil
+}
+
+func (server *RemoteServer) Addr() net.Addr {
+	return server.listener.Addr()
+}
+
+func (server *RemoteServer) Close() error {
+	server.lock.Lock()
+	server.closed = true
+	for conn := range server.conns {
+		conn.Close()
+	}
+	server.conns = make(map[net.Conn]struct{})
+	server.lock.Unlock()
+	return server.listener.Close()
+}
+
+func (server *RemoteServer) accept() {
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		server.lock.Lock()
+		if server.closed {
+			server.lock.Unlock()
+			conn.Close()
+			return
+		}
+		server.conns[conn] = struct{}{}
+		server.lock.Unlock()
+		go server.serve(conn)
+	}
+}
+
+// challenge checks the dialing system knows the secret, then proves it
+// knows it too
+func (server *RemoteServer) challenge(conn net.Conn) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+
+	conn.SetDeadline(time.Now().Add(remoteDialTimeout))
+	defer conn.SetDeadline(time.Time{})
+	if err := writeEnvelope(conn, &envelope{Kind: envelopeHello, Nonce: nonce}); err != nil {
+		return err
+	}
+	env, err := readEnvelope(conn, maxHandshakeFrame)
+	if err != nil {
+		return err
+	}
+	secret := server.system.secret()
+	if env.Kind != envelopeHello || len(env.Nonce) != len(nonce) || !hmac.Equal(env.Proof, proof(secret, "dial", nonce)) {
+		return ErrRemoteUnauthorized
+	}
+	return writeEnvelope(conn, &envelope{Kind: envelopeHello, Proof: proof(secret, "listen", env.Nonce)})
+}
+
+func (server *RemoteServer) serve(conn net.Conn) {
+	defer func() {
+		server.lock.Lock()
+		delete(server.conns, conn)
+		server.lock.Unlock()
+		conn.Close()
+	}()
+
+	if err := server.challenge(conn); err != nil {
+		return
+	}
+
+	writeLock := &sync.Mutex{}
+	requires := make(chan struct{}, server.maxRequires)
+	for {
+		env, err := readEnvelope(conn, MaxRemoteFrame)
+		if err != nil {
+			return
+		}
+
+		switch env.Kind {
+		case envelopeRequest:
+			if !server.exported[env.Target] {
+				server.system.deadLetter(env.Target, &Event{event: env.Event, sender: env.Sender}, DEAD_LETTER_NO_ROUTE, server.unexported(env.Target))
+				continue
+			}
+			event, err := server.system.decode(env.Event)
+			if err != nil {
+				server.system.deadLetter(env.Target, &Event{event: env.Event, sender: env.Sender}, DEAD_LETTER_SERIALIZATION, err)
+				continue
+			}
+			server.system.send(env.Target, &Event{event: event, sender: env.Sender})
+		case envelopeRequire:
+			requires <- struct{}{}
+			go func(env *envelope) {
+				defer func() { <-requires }()
+				server.require(conn, writeLock, env)
+			}(env)
+		}
+	}
+}
+
+func (server *RemoteServer) unexported(name string) error {
+	return errors.New(fmt.Sprintf("actor %s is not exported", name))
+}
+
+func (server *RemoteServer) require(conn net.Conn, writeLock *sync.Mutex, env *envelope) {
+	timeout := env.Timeout
+	if timeout <= 0 {
+		timeout = int(RemoteRequireTimeout / time.Millisecond)
+	}
+
+	response := &envelope{Kind: envelopeResponse, ID: env.ID, Target: env.Target}
+	registry := server.system.serializerRegistry()
+	if !server.exported[env.Target] {
+		response.Error = server.unexported(env.Target).Error()
+	} else if event, err := server.system.decode(env.Event); err != nil {
+		response.Error = fmt.Sprintf("unable to deserialize event: %v", err)
+	} else if rst, err := server.system.requireEvent(env.Target, &Event{event: event, sender: env.Sender}, timeout); err != nil {
+		response.Error = err.Error()
+	} else if response.Event, err = registry.Serialize(rst); err != nil {
+		response.Error = fmt.Sprintf("unable to serialize response: %v", err)
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	writeEnvelope(conn, response)
+}
+
+// requireEvent is require for a prepared event
+func (system *ActorSystem) requireEvent(actorName string, event *Event, timeout int) (interface{}, error) {
+	ch := make(chan interface{}, 1)
+	event.responseChan = ch
+	event.timeout = time.Duration(timeout) * time.Millisecond
+	if err := system.send(actorName, event); err != nil {
+		return nil, err
+	}
+	return system.awaitResponse(actorName, event, ch, timeout)
+}
+
+// pendingRequire waits for its response on conn, failed if conn breaks
+type pendingRequire struct {
+	actorName string
+	event     *Event
+	conn      net.Conn
+}
+
+// remoteNode is the connection to a remote system, shared by all of its
+// proxies. It dials on demand, so a broken connection is replaced by the
+// next message.
+type remoteNode struct {
+	name    string
+	address string
+	system  *ActorSystem
+
+	conn    net.Conn
+	seq     uint64
+	pending map[uint64]*pendingRequire
+	proxies int
+	lock    sync.Mutex
+
+	// dialLock lets a single dial run, without holding up responses read
+	// meanwhile, writeLock keeps frames whole
+	dialLock  sync.Mutex
+	writeLock sync.Mutex
+}
+
+// AddRemote registers names of the system listening on address as
+// node/name, e.g. node2/http. Requests and requires to them go over a single
+// connection to the node.
+func (system *ActorSystem) AddRemote(node string, address string, names ...string) error {
+	system.lock.Lock()
+	remote, ok := system.remotes[node]
+	if !ok {
+		remote = &remoteNode{
+			name:    node,
+			address: address,
+			system:  system,
+			pending: make(map[uint64]*pendingRequire),
+		}
+		system.remotes[node] = remote
+	} else if remote.address != address {
+		system.lock.Unlock()
+		return errors.New(fmt.Sprintf("node %s is at %s already", node, remote.address))
+	}
+	system.lock.Unlock()
+
+	var added []*remoteActor
+	for _, name := range names {
+		proxy := &remoteActor{remote, name}
+		if _, err := system.AddActor(node+"/"+name, proxy); err != nil {
+			for _, proxy := range added {
+				system.RemoveActor(node+"/"+proxy.target, proxy)
+			}
+			if !ok && len(added) == 0 {
+				system.lock.Lock()
+				if system.remotes[node] == remote {
+					delete(system.remotes, node)
+				}
+				system.lock.Unlock()
+			}
+			return err
+		}
+		added = append(added, proxy)
+	}
+	return nil
+}
+
+// connection is the current connection, dialed if there is none
+func (remote *remoteNode) connection() (net.Conn, error) {
+	remote.lock.Lock()
+	conn := remote.conn
+	remote.lock.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+
+	remote.dialLock.Lock()
+	defer remote.dialLock.Unlock()
+	remote.lock.Lock()
+	conn = remote.conn
+	remote.lock.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+
+	conn, err := net.DialTimeout("tcp", remote.address, remoteDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := remote.answer(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	remote.lock.Lock()
+	remote.conn = conn
+	remote.lock.Unlock()
+	go remote.read(conn)
+	return conn, nil
+}
+
+// answer proves to the node the secret is known, and checks the node knows
+// it as well
+func (remote *remoteNode) answer(conn net.Conn) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+
+	conn.SetDeadline(time.Now().Add(remoteDialTimeout))
+	defer conn.SetDeadline(time.Time{})
+	env, err := readEnvelope(conn, maxHandshakeFrame)
+	if err != nil {
+		return err
+	}
+	if env.Kind != envelopeHello {
+		return ErrRemoteUnauthorized
+	}
+	secret := remote.system.secret()
+	if err := writeEnvelope(conn, &envelope{Kind: envelopeHello, Proof: proof(secret, "dial", env.Nonce), Nonce: nonce}); err != nil {
+		return err
+	}
+	if env, err = readEnvelope(conn, maxHandshakeFrame); err != nil {
+		return err
+	}
+	if env.Kind != envelopeHello || !hmac.Equal(env.Proof, proof(secret, "listen", nonce)) {
+		return ErrRemoteUnauthorized
+	}
+	return nil
+}
+
+// write sends env, on a new connection if the current one turns out broken.
+// A require is registered as pending on the connection it is written to,
+// under env.ID.
+func (remote *remoteNode) write(env *envelope, pending *pendingRequire) error {
+	if pending != nil {
+		env.ID = atomic.AddUint64(&remote.seq, 1)
+	}
+	for retry := 0; ; retry++ {
+		conn, err := remote.connection()
+		if err != nil {
+			return ErrRemoteUnavailable
+		}
+		if pending != nil {
+			pending.conn = conn
+			remote.lock.Lock()
+			remote.pending[env.ID] = pending
+			remote.lock.Unlock()
+		}
+
+		remote.writeLock.Lock()
+		err = writeEnvelope(conn, env)
+		remote.writeLock.Unlock()
+		if err == nil {
+			return nil
+		}
+
+		if pending != nil && remote.take(env.ID) == nil {
+			// failed along with the connection already
+			return err
+		}
+		if _, broken := err.(net.Error); !broken || retry > 0 {
+			return err
+		}
+		remote.lock.Lock()
+		remote.drop(conn)
+		remote.lock.Unlock()
+	}
+}
+
+func (remote *remoteNode) read(conn net.Conn) {
+	for {
+		env, err := readEnvelope(conn, MaxRemoteFrame)
+		if err != nil {
+			remote.lock.Lock()
+			remote.drop(conn)
+			remote.lock.Unlock()
+			return
+		}
+
+		pending := remote.take(env.ID)
+		if env.Error != "" {
+			if pending != nil {
+				pending.event.respond(&failedResponse{&RemoteError{remote.name, env.Error}})
+			}
+			continue
+		}
+
+		rst, err := remote.system.decode(env.Event)
+		switch {
+		case err != nil && pending != nil:
+			pending.event.respond(&failedResponse{err})
+		case err != nil:
+			remote.system.deadLetter(remote.name+"/"+env.Target, &Event{event: env.Event}, DEAD_LETTER_SERIALIZATION, err)
+		case pending != nil:
+			remote.system.reply(pending.actorName, pending.event, rst)
+		default:
+			remote.orphan(remote.name+"/"+env.Target, rst)
+		}
+	}
+}
+
+// orphan dead letters a response whose require has been cleaned up already,
+// the require itself is not known any more
+func (remote *remoteNode) orphan(actorName string, response interface{}) {
+	atomic.AddUint64(&remote.system.orphaned, 1)
+	remote.system.deadLetter(actorName, &Event{}, DEAD_LETTER_ORPHANED, &OrphanedResponseError{
+		ActorName: actorName,
+		Response:  response,
+	})
+}
+
+// drop forgets a broken connection and fails the requires waiting on it,
+// requires remote.lock held
+func (remote *remoteNode) drop(conn net.Conn) {
+	conn.Close()
+	if remote.conn == conn {
+		remote.conn = nil
+	}
+	for id, pending := range remote.pending {
+		if pending.conn == conn {
+			delete(remote.pending, id)
+			pending.event.respond(&failedResponse{ErrRemoteUnavailable})
+		}
+	}
+}
+
+func (remote *remoteNode) take(id uint64) *pendingRequire {
+	remote.lock.Lock()
+	defer remote.lock.Unlock()
+	pending := remote.pending[id]
+	delete(remote.pending, id)
+	return pending
+}
+
+// require waits as long as the caller does, the node gives up then as well.
+// The pending require is kept a little longer for a response on its way to
+// still be told orphaned.
+func (remote *remoteNode) require(actorName string, target string, event *Event) {
+	timeout := RemoteRequireTimeout
+	if event.timeout > 0 {
+		timeout = event.timeout
+	}
+	if event.ctx != nil {
+		if deadline, ok := event.ctx.Deadline(); ok && time.Until(deadline) < timeout {
+			timeout = time.Until(deadline)
+		}
+	}
+
+	payload, err := remote.system.serializerRegistry().Serialize(event.event)
+	if err != nil {
+		event.respond(&failedResponse{err})
+		return
+	}
+
+	env := &envelope{
+		Kind:    envelopeRequire,
+		Target:  target,
+		Sender:  event.sender,
+		Timeout: int(timeout / time.Millisecond),
+		Event:   payload,
+	}
+	if err := remote.write(env, &pendingRequire{actorName: actorName, event: event}); err != nil {
+		event.respond(&failedResponse{err})
+		return
+	}
+
+	id := env.ID
+	time.AfterFunc(timeout+remoteDialTimeout, func() {
+		if pending := remote.take(id); pending != nil {
+			event.respond(&failedResponse{errors.New(fmt.Sprintf("require to %s timeout", actorName))})
+		}
+	})
+}
+
+// plug counts a proxy of the node, registering the node again if its last
+// proxy has gone meanwhile
+func (remote *remoteNode) plug() {
+	remote.system.lock.Lock()
+	defer remote.system.lock.Unlock()
+	remote.lock.Lock()
+	defer remote.lock.Unlock()
+
+	remote.proxies++
+	if _, ok := remote.system.remotes[remote.name]; !ok {
+		remote.system.remotes[remote.name] = remote
+	}
+}
+
+func (remote *remoteNode) release() {
+	remote.system.lock.Lock()
+	defer remote.system.lock.Unlock()
+	remote.lock.Lock()
+	defer remote.lock.Unlock()
+
+	remote.proxies--
+	if remote.proxies > 0 {
+		return
+	}
+	if remote.conn != nil {
+		remote.drop(remote.conn)
+	}
+	if remote.system.remotes[remote.name] == remote {
+		delete(remote.system.remotes, remote.name)
+	}
+}
+
+// remoteActor stands for an actor of a remote node. It doesn't wait for
+// responses, they are replied from the connection as they come.
+type remoteActor struct {
+	node   *remoteNode
+	target string
+}
+
+func (actor *remoteActor) OnPlugin(system *ActorSystem) {
+	actor.node.plug()
+}
+
+func (actor *remoteActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return nil
+}
+
+func (actor *remoteActor) OnPullout(system *ActorSystem) {
+	actor.node.release()
+}
+
+func (actor *remoteActor) ReceiveMessage(msg *Message) {
+	if msg.EventType == EVENT_REQUIRE {
+		actor.node.require(msg.Self, actor.target, msg.event)
+		return
+	}
+
+	payload, err := msg.System.serializerRegistry().Serialize(msg.Event)
+	if err != nil {
+		msg.System.deadLetter(msg.Self, msg.event, DEAD_LETTER_SERIALIZATION, err)
+		return
+	}
+	if err := actor.node.write(&envelope{
+		Kind:   envelopeRequest,
+		Target: actor.target,
+		Sender: msg.Sender,
+		Event:  payload,
+	}, nil); err != nil {
+		msg.System.deadLetter(msg.Self, msg.event, DEAD_LETTER_NO_ROUTE, err)
+	}
+}
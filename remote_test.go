@@ -0,0 +1,323 @@
+package goactor
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type remotePoint struct {
+	X, Y int
+}
+
+const remoteTestSecret = "s3cret"
+
+func newRemotePair(t *testing.T, names ...string) (local *ActorSystem, remote *ActorSystem, server *RemoteServer) {
+	remote = NewDefaultActorSystem()
+	remote.SetRemoteSecret(remoteTestSecret)
+	server, err := remote.Listen("127.0.0.1:0", WithExported(names...))
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+
+	local = NewDefaultActorSystem()
+	local.SetRemoteSecret(remoteTestSecret)
+	if err := local.AddRemote("node2", server.Addr().String(), names...); err != nil {
+		t.Fatalf("add remote failed: %v", err)
+	}
+	return local, remote, server
+}
+
+func TestRemoteRequire(t *testing.T) {
+	RegisterSerializer(&remotePoint{}, &MsgPackSerializer{})
+	local, remote, server := newRemotePair(t, "echo", "missing")
+	counter := &slowActor{0, new(int32), new(int32)}
+	remote.AddActor("echo", counter)
+	defer server.Close()
+	defer remote.Shutdown()
+	defer local.Shutdown()
+
+	if rst, err := local.Require("node2/echo", "hello", 1000); err != nil || rst != "hello" {
+		t.Errorf("unexpected response %v, %v", rst, err)
+	}
+	rst, err := local.Require("node2/echo", &remotePoint{1, 2}, 1000)
+	if point, ok := rst.(*remotePoint); err != nil || !ok || *point != (remotePoint{1, 2}) {
+		t.Errorf("unexpected response %v, %v", rst, err)
+	}
+
+	local.Request("node2/echo", 3)
+	for i := 0; i < 100 && atomic.LoadInt32(counter.handled) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(counter.handled) != 3 {
+		t.Errorf("expect 3 events handled remotely, got %d", *counter.handled)
+	}
+
+	_, err = local.Require("node2/missing", "hello", 1000)
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) || !strings.Contains(remoteErr.Message, "missing") {
+		t.Errorf("expect a remote error, got %v", err)
+	}
+
+	server.lock.Lock()
+	conns := len(server.conns)
+	server.lock.Unlock()
+	if conns != 1 {
+		t.Errorf("expect the connection reused, got %d", conns)
+	}
+}
+
+func TestRemoteTimeout(t *testing.T) {
+	local, remote, server := newRemotePair(t, "stuck")
+	remote.AddActor("stuck", newGateActor())
+	defer server.Close()
+	defer remote.Shutdown()
+	defer local.Shutdown()
+
+	start := time.Now()
+	if _, err := local.Require("node2/stuck", "hello", 50); err == nil {
+		t.Error("expect timeout")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("timeout took %v", time.Since(start))
+	}
+}
+
+func TestRemoteReconnect(t *testing.T) {
+	local, remote, server := newRemotePair(t, "echo")
+	remote.AddActor("echo", new(mockActor))
+	defer remote.Shutdown()
+	defer local.Shutdown()
+
+	if _, err := local.Require("node2/echo", "before", 1000); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	address := server.Addr().String()
+	server.Close()
+	if _, err := local.Require("node2/echo", "down", 1000); err == nil {
+		t.Error("expect the node unavailable")
+	}
+
+	server, err := remote.Listen(address, WithExported("echo"))
+	if err != nil {
+		t.Fatalf("listen again failed: %v", err)
+	}
+	defer server.Close()
+
+	var rst interface{}
+	for i := 0; i < 10; i++ {
+		if rst, err = local.Require("node2/echo", "after", 1000); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil || rst != "after" {
+		t.Errorf("expect reconnected, got %v, %v", rst, err)
+	}
+}
+
+func TestRemoteUnknownType(t *testing.T) {
+	local, remote, server := newRemotePair(t, "echo")
+	remote.AddActor("echo", new(mockActor))
+	defer server.Close()
+	defer remote.Shutdown()
+	defer local.Shutdown()
+
+	_, err := local.Require("node2/echo", &unregisteredEvent{"x"}, 1000)
+	var unknown *UnknownTypeError
+	if !errors.As(err, &unknown) {
+		t.Errorf("expect unknown type error, got %v", err)
+	}
+}
+
+func TestRemoteAccess(t *testing.T) {
+	local, remote, server := newRemotePair(t, "echo")
+	remote.AddActor("echo", new(mockActor))
+	remote.AddActor("private", new(mockActor))
+	local.AddRemote("node2", server.Addr().String(), "private")
+	defer server.Close()
+	defer remote.Shutdown()
+	defer local.Shutdown()
+
+	_, err := local.Require("node2/private", "hello", 1000)
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) || !strings.Contains(remoteErr.Message, "not exported") {
+		t.Errorf("expect an unexported actor to be unreachable, got %v", err)
+	}
+
+	stranger := NewDefaultActorSystem()
+	stranger.SetRemoteSecret("guess")
+	stranger.AddRemote("node2", server.Addr().String(), "echo")
+	defer stranger.Shutdown()
+	if _, err := stranger.Require("node2/echo", "hello", 1000); err != ErrRemoteUnavailable {
+		t.Errorf("expect a wrong secret to be refused, got %v", err)
+	}
+	stranger.SetRemoteSecret(remoteTestSecret)
+	if rst, err := stranger.Require("node2/echo", "hello", 1000); err != nil || rst != "hello" {
+		t.Errorf("expect the right secret to be accepted, got %v, %v", rst, err)
+	}
+}
+
+func TestRemoteNeedsSecret(t *testing.T) {
+	system := NewDefaultActorSystem()
+	if _, err := system.Listen("127.0.0.1:0"); err != ErrNoRemoteSecret {
+		t.Errorf("expect listening without secret refused, got %v", err)
+	}
+
+	// a node not knowing the secret is not talked to
+	listener := fakeNode(t, "guess", 0, 0, "forged")
+	defer listener.Close()
+	system.SetRemoteSecret(remoteTestSecret)
+	system.AddRemote("fake", listener.Addr().String(), "echo")
+	defer system.Shutdown()
+	if rst, err := system.Require("fake/echo", "hello", 1000); err != ErrRemoteUnavailable {
+		t.Errorf("expect an impostor node refused, got %v, %v", rst, err)
+	}
+}
+
+type panickySerializer struct {
+	JSONSerializer
+}
+
+func (serializer *panickySerializer) Name() string {
+	return "panicky"
+}
+
+func (serializer *panickySerializer) Unmarshal(data []byte, typ reflect.Type) (interface{}, error) {
+	panic("corrupt")
+}
+
+type panickyEvent struct {
+	Name string
+}
+
+func TestRemoteSurvivesBadPayload(t *testing.T) {
+	RegisterSerializer(&panickyEvent{}, &panickySerializer{})
+	local, remote, server := newRemotePair(t, "echo")
+	remote.AddActor("echo", new(mockActor))
+	defer server.Close()
+	defer remote.Shutdown()
+	defer local.Shutdown()
+
+	if _, err := local.Require("node2/echo", &panickyEvent{"x"}, 1000); err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("expect the payload to fail, got %v", err)
+	}
+	if rst, err := local.Require("node2/echo", "after", 1000); err != nil || rst != "after" {
+		t.Errorf("expect the server alive, got %v, %v", rst, err)
+	}
+}
+
+func TestRemoteCallerTimeout(t *testing.T) {
+	local, remote, server := newRemotePair(t, "stuck")
+	gate := newGateActor()
+	remote.AddActor("stuck", gate)
+	defer server.Close()
+	defer remote.Shutdown()
+	defer local.Shutdown()
+	defer close(gate.gate)
+
+	if _, err := local.Require("node2/stuck", "hello", 50); err == nil {
+		t.Error("expect timeout")
+	}
+	// the remote side waits no longer than the caller
+	for i := 0; i < 50 && remote.AbandonedRequires() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if remote.AbandonedRequires() != 1 {
+		t.Error("expect the remote require abandoned along with the caller")
+	}
+}
+
+// fakeHandshake plays the listening end of the handshake, knowing secret
+func fakeHandshake(conn net.Conn, secret string) {
+	nonce := []byte("server nonce")
+	writeEnvelope(conn, &envelope{Kind: envelopeHello, Nonce: nonce})
+	if env, err := readEnvelope(conn, maxHandshakeFrame); err == nil {
+		writeEnvelope(conn, &envelope{Kind: envelopeHello, Proof: proof([]byte(secret), "listen", env.Nonce)})
+	}
+}
+
+// fakeNode answers the handshake then every require with response, after
+// delay and under the id of the require plus shift
+func fakeNode(t *testing.T, secret string, delay time.Duration, shift uint64, response interface{}) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fakeHandshake(conn, secret)
+		for {
+			env, err := readEnvelope(conn, MaxRemoteFrame)
+			if err != nil {
+				return
+			}
+			time.Sleep(delay)
+			payload, _ := DefaultSerializers.Serialize(response)
+			writeEnvelope(conn, &envelope{Kind: envelopeResponse, ID: env.ID + shift, Target: env.Target, Event: payload})
+		}
+	}()
+	return listener
+}
+
+func TestRemoteLateResponse(t *testing.T) {
+	for _, shift := range []uint64{0, 1000} {
+		listener := fakeNode(t, remoteTestSecret, 100*time.Millisecond, shift, "late")
+		store := NewDeadLetterStore(10)
+		local := NewActorSystem(NewFullQualifiedNameWithRandomBalancerRouter(), store)
+		local.SetRemoteSecret(remoteTestSecret)
+		local.AddRemote("fake", listener.Addr().String(), "slow")
+
+		if _, err := local.Require("fake/slow", "hello", 50); err == nil {
+			t.Error("expect timeout")
+		}
+		var orphans []*DeadLetter
+		for i := 0; i < 50 && len(orphans) == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+			orphans = store.Filter(func(letter *DeadLetter) bool { return letter.Reason == DEAD_LETTER_ORPHANED })
+		}
+		if len(orphans) != 1 || orphans[0].ActorName != "fake/slow" || orphans[0].Response != "late" {
+			t.Errorf("expect the late response orphaned, got %v", orphans)
+		}
+		local.Shutdown()
+		listener.Close()
+	}
+}
+
+func TestRemoteProxyReplaced(t *testing.T) {
+	local, remote, server := newRemotePair(t, "echo")
+	remote.AddActor("echo", new(mockActor))
+	defer server.Close()
+	defer remote.Shutdown()
+	defer local.Shutdown()
+
+	proxy := local.registry.lookup("node2/echo")[0].actorImpl.(*remoteActor)
+	node := proxy.node
+	done, err := local.ReplaceActor("node2/echo", proxy, &remoteActor{node, "echo"})
+	if err != nil {
+		t.Fatalf("replace failed: %v", err)
+	}
+	<-done
+
+	local.lock.RLock()
+	registered := local.remotes["node2"] == node
+	local.lock.RUnlock()
+	node.lock.Lock()
+	proxies := node.proxies
+	node.lock.Unlock()
+	if !registered || proxies != 1 {
+		t.Errorf("expect the node kept with 1 proxy, got %v, %d", registered, proxies)
+	}
+	if rst, err := local.Require("node2/echo", "hello", 1000); err != nil || rst != "hello" {
+		t.Errorf("unexpected response %v, %v", rst, err)
+	}
+}
@@ -0,0 +1,84 @@
+package goactor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StateMigrator lets an implementation being replaced hand its state to
+// its successor, right before its OnPullout
+type StateMigrator interface {
+	MigrateTo(system *ActorSystem, successor ActorInterface)
+}
+
+type swapRequest struct {
+	successor ActorInterface
+	done      chan struct{}
+}
+
+// ReplaceActor swaps the implementation of the instance registered as
+// oldImpl under name. The instance keeps its mailbox, so whatever is queued
+// and whatever arrives meanwhile is processed by newImpl. The event oldImpl
+// is working on, if any, is finished first. The returned channel is closed
+// once newImpl is plugged in, or the instance stopped before that.
+func (system *ActorSystem) ReplaceActor(name string, oldImpl ActorInterface, newImpl ActorInterface) (done <-chan struct{}, err error) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+
+	for _, actor := range system.registry.lookup(name) {
+		if actor.actorImpl != oldImpl {
+			continue
+		}
+
+		request := &swapRequest{
+			successor: newImpl,
+			done:      make(chan struct{}),
+		}
+		actor.mailboxLock.Lock()
+		if actor.swap != nil {
+			actor.mailboxLock.Unlock()
+			return nil, errors.New(fmt.Sprintf("actor %s is being replaced already", name))
+		}
+		actor.swap = request
+		actor.mailboxLock.Unlock()
+
+		actor.actorImpl = newImpl
+		actor.accept(newImpl)
+		system.registry.reindex(actor)
+
+		// wake the loop up in case the mailbox is empty
+		actor.wake()
+		return request.done, nil
+	}
+	return nil, errors.New("actor not in system")
+}
+
+// applySwap runs on the actor goroutine between two events
+func (actor *innerActor) applySwap() {
+	actor.mailboxLock.Lock()
+	request := actor.swap
+	actor.swap = nil
+	actor.mailboxLock.Unlock()
+	if request == nil {
+		return
+	}
+
+	if migrator, ok := actor.running.(StateMigrator); ok {
+		migrator.MigrateTo(actor.system, request.successor)
+	}
+	actor.reset()
+	actor.running.OnPullout(actor.system)
+	actor.running = request.successor
+	actor.running.OnPlugin(actor.system)
+	close(request.done)
+}
+
+func (actor *innerActor) dropSwap() {
+	actor.mailboxLock.Lock()
+	request := actor.swap
+	actor.swap = nil
+	actor.mailboxLock.Unlock()
+	if request != nil {
+		close(request.done)
+	}
+}
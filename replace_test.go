@@ -0,0 +1,76 @@
+package goactor
+
+import (
+	"testing"
+)
+
+type versionActor struct {
+	version  int
+	state    []interface{}
+	plugged  bool
+	unplug   bool
+	migrated bool
+}
+
+func (actor *versionActor) OnPlugin(system *ActorSystem) {
+	actor.plugged = true
+}
+
+func (actor *versionActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	if gate, ok := event.(chan struct{}); ok {
+		<-gate
+	}
+	actor.state = append(actor.state, event)
+	return actor.version
+}
+
+func (actor *versionActor) OnPullout(system *ActorSystem) {
+	actor.unplug = true
+}
+
+func (actor *versionActor) MigrateTo(system *ActorSystem, successor ActorInterface) {
+	if next, ok := successor.(*versionActor); ok {
+		next.state = actor.state
+		next.migrated = true
+	}
+}
+
+func TestReplaceActor(t *testing.T) {
+	system := NewDefaultActorSystem()
+	v1 := &versionActor{version: 1}
+	v2 := &versionActor{version: 2}
+	system.AddActor("versioned", v1)
+	defer system.Shutdown()
+
+	// keep v1 busy, so later events stay queued while replacing
+	gate := make(chan struct{})
+	system.Request("versioned", gate)
+	queued := system.RequireAsync("versioned", "queued", 1000)
+
+	done, err := system.ReplaceActor("versioned", v1, v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := system.ReplaceActor("versioned", v1, v2); err == nil {
+		t.Error("replaced actor found by old implementation")
+	}
+	after := system.RequireAsync("versioned", "after", 1000)
+	close(gate)
+	<-done
+
+	if rst, err := queued.Await(); rst != 2 || err != nil {
+		t.Errorf("queued event not handed to new implementation, got %v, %v", rst, err)
+	}
+	if rst, err := after.Await(); rst != 2 || err != nil {
+		t.Errorf("new event not handled by new implementation, got %v, %v", rst, err)
+	}
+
+	if !v1.unplug || !v2.plugged || !v2.migrated || len(v2.state) != 3 {
+		t.Errorf("unexpected lifecycle, v1 unplugged %v, v2 plugged %v, migrated %v, state %v", v1.unplug, v2.plugged, v2.migrated, v2.state)
+	}
+
+	system.RemoveActor("versioned", v2)
+	if system.instance("versioned", v2) != nil {
+		t.Error("unable to remove by new implementation")
+	}
+}
@@ -1,7 +1,10 @@
 package goactor
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 )
 
 type singleBalancer struct{}
@@ -40,3 +43,154 @@ func TestFullQualifiedNameRouter(t *testing.T) {
 		t.Error("Unexpected E found")
 	}
 }
+
+func TestPathRouter(t *testing.T) {
+	router := NewPathRouter(&singleBalancer{})
+
+	actors := make(map[string][]*innerActor)
+	for _, name := range []string{"orders/eu", "orders/us", "orders/us/west", "payments", "payments/us"} {
+		actors[name] = []*innerActor{&innerActor{name: name}}
+	}
+
+	cases := map[string]string{
+		"orders/eu":         "orders/eu",
+		"payments/eu":       "payments",
+		"payments/eu/de":    "payments",
+		"payments/us/east":  "payments/us",
+		"orders/*":          "orders/eu",
+		"orders/u?":         "orders/us",
+		"orders/**":         "orders/eu",
+		"orders/*/west":     "orders/us/west",
+		"*/us":              "orders/us",
+		"orders/us/west/la": "orders/us/west",
+	}
+	for name, expect := range cases {
+		if actor, err := router.Route(name, actors); err != nil || actor.name != expect {
+			t.Errorf("%s: expect %s, got %v, %v", name, expect, actor, err)
+		}
+	}
+
+	for _, name := range []string{"shipping", "orders", "orders/*/east", "shipping/*"} {
+		if actor, err := router.Route(name, actors); actor != nil || err == nil {
+			t.Errorf("unexpected %s found", name)
+		}
+	}
+
+	matched, err := router.RouteAll("orders/**", nil, actors)
+	if err != nil || len(matched) != 3 {
+		t.Errorf("expect 3 matches for orders/**, got %d, %v", len(matched), err)
+	}
+	if matched, _ := router.RouteAll("orders/*", nil, actors); len(matched) != 2 {
+		t.Errorf("expect 2 matches for orders/*, got %d", len(matched))
+	}
+}
+
+func TestRequestAll(t *testing.T) {
+	system := NewActorSystem(NewPathWithRandomBalancerRouter(), NewConsoleDeadLetterProcessor())
+	eu := &slowActor{0, new(int32), new(int32)}
+	us := &slowActor{0, new(int32), new(int32)}
+	system.AddActor("orders/eu", eu)
+	system.AddActor("orders/us", us)
+	defer system.Shutdown()
+
+	if err := system.RequestAll("orders/*", 1); err != nil {
+		t.Errorf("fan out failed: %v", err)
+	}
+	if _, err := system.Require("orders/eu/paris", 2, 1000); err != nil {
+		t.Errorf("prefix fallback failed: %v", err)
+	}
+	if err := system.RequestAll("shipping/*", 3); err == nil {
+		t.Error("expect no match error")
+	}
+
+	system.Require("orders/us", 4, 1000)
+	if *eu.handled != 2 || *us.handled != 2 {
+		t.Errorf("expect 2 events each, got %d and %d", *eu.handled, *us.handled)
+	}
+}
+
+type pingEvent struct{}
+
+type namedEvent string
+
+func (event namedEvent) String() string {
+	return string(event)
+}
+
+type acceptingActor struct {
+	accepts []reflect.Type
+	tag     string
+}
+
+func (actor *acceptingActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *acceptingActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	return actor.tag
+}
+
+func (actor *acceptingActor) OnPullout(system *ActorSystem) {}
+
+func (actor *acceptingActor) Accepts() []reflect.Type {
+	return actor.accepts
+}
+
+func TestTypeRouter(t *testing.T) {
+	processor := &recordDeadLetterProcessor{make(chan interface{}, 10)}
+	system := NewActorSystem(NewTypeWithRandomBalancerRouter(), processor)
+	system.AddActor("ping", &acceptingActor{[]reflect.Type{reflect.TypeOf(&pingEvent{})}, "ping"})
+	system.AddActor("stringer", &acceptingActor{[]reflect.Type{reflect.TypeOf((*fmt.Stringer)(nil)).Elem()}, "stringer"})
+	system.AddActor("named", &acceptingActor{[]reflect.Type{reflect.TypeOf(namedEvent(""))}, "named"})
+	defer system.Shutdown()
+
+	if rst, err := system.RequireByType(&pingEvent{}, 1000); rst != "ping" || err != nil {
+		t.Errorf("expect ping, got %v, %v", rst, err)
+	}
+
+	if rst, err := system.RequireByType(namedEvent("a"), 1000); rst != "named" || err != nil {
+		t.Errorf("exact type should win over interface, got %v, %v", rst, err)
+	}
+
+	if rst, err := system.RequireByType(time.Second, 1000); rst != "stringer" || err != nil {
+		t.Errorf("expect stringer for time.Duration, got %v, %v", rst, err)
+	}
+
+	if rst, err := system.Require("ping", 1, 1000); rst != "ping" || err != nil {
+		t.Errorf("named routing broken, got %v, %v", rst, err)
+	}
+
+	if err := system.RequestByType(42); err == nil {
+		t.Error("unhandled type routed")
+	}
+	if event := <-processor.events; event != 42 {
+		t.Errorf("expect 42 in dead letter, got %v", event)
+	}
+}
+
+func TestTypeRouterFollowsChanges(t *testing.T) {
+	system := NewActorSystem(NewTypeWithRandomBalancerRouter(), &recordDeadLetterProcessor{make(chan interface{}, 10)})
+	ping := &acceptingActor{[]reflect.Type{reflect.TypeOf(&pingEvent{})}, "ping"}
+	system.AddActor("ping", ping)
+	defer system.Shutdown()
+
+	if rst, err := system.RequireByType(&pingEvent{}, 1000); rst != "ping" || err != nil {
+		t.Errorf("expect ping, got %v, %v", rst, err)
+	}
+
+	named := &acceptingActor{[]reflect.Type{reflect.TypeOf(namedEvent(""))}, "named"}
+	done, err := system.ReplaceActor("ping", ping, named)
+	if err != nil {
+		t.Fatalf("replace failed: %v", err)
+	}
+	<-done
+	if _, err := system.RequireByType(&pingEvent{}, 1000); err == nil {
+		t.Error("replaced instance still routed its old type")
+	}
+	if rst, err := system.RequireByType(namedEvent("a"), 1000); rst != "named" || err != nil {
+		t.Errorf("expect named after replace, got %v, %v", rst, err)
+	}
+
+	system.RemoveActor("ping", named)
+	if _, err := system.RequireByType(namedEvent("a"), 1000); err == nil {
+		t.Error("removed instance still routed")
+	}
+}
@@ -9,6 +9,27 @@ type Router interface {
 	Route(actorName string, actors map[string][]*innerActor) (actor *innerActor, err error)
 }
 
+// EventRouter is picked over Route when implemented, for routers which
+// need the event itself to decide
+type EventRouter interface {
+	Router
+	RouteEvent(actorName string, event interface{}, actors map[string][]*innerActor) (actor *innerActor, err error)
+}
+
+// RegistryRouter is picked over Route and RouteEvent when implemented, for
+// routers which look up the names they need rather than go through all of
+// them
+type RegistryRouter interface {
+	Router
+	RouteRegistry(actorName string, event interface{}, registry *actorRegistry) (actor *innerActor, err error)
+}
+
+// MultiRouter fans a pattern out to one instance of every matching name
+type MultiRouter interface {
+	Router
+	RouteAll(pattern string, event interface{}, actors map[string][]*innerActor) (matched []*innerActor, err error)
+}
+
 type FullQualifiedNameRouter struct {
 	balancer Balancer
 }
@@ -30,3 +51,35 @@ func (router *FullQualifiedNameRouter) Route(actorName string, actors map[string
 		return nil, errors.New(fmt.Sprintf("Unable to find actor match %s", actorName))
 	}
 }
+
+func (router *FullQualifiedNameRouter) RouteEvent(actorName string, event interface{}, actors map[string][]*innerActor) (actor *innerActor, err error) {
+	if _, ok := actors[actorName]; ok {
+		return choose(router.balancer, actorName, event, actors[actorName]), nil
+	}
+	return router.Route(actorName, actors)
+}
+
+func (router *FullQualifiedNameRouter) RouteRegistry(actorName string, event interface{}, registry *actorRegistry) (actor *innerActor, err error) {
+	if actors := registry.lookup(actorName); len(actors) > 0 {
+		return choose(router.balancer, actorName, event, actors), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unable to find actor match %s", actorName))
+}
+
+func (router *FullQualifiedNameRouter) forget(actorName string) {
+	forget(router.balancer, actorName)
+}
+
+func choose(balancer Balancer, actorName string, event interface{}, actors []*innerActor) *innerActor {
+	if eventBalancer, ok := balancer.(EventBalancer); ok {
+		return eventBalancer.ChooseEvent(actorName, event, actors)
+	}
+	return balancer.Choose(actorName, actors)
+}
+
+// forget passes actorName on to target if it keeps state per name
+func forget(target interface{}, actorName string) {
+	if forgetter, ok := target.(forgetter); ok {
+		forgetter.forget(actorName)
+	}
+}
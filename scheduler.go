@@ -0,0 +1,142 @@
+package goactor
+
+import (
+	"sync"
+	"time"
+)
+
+// Schedule is the handle of a scheduled message. It is cancelled
+// automatically once its target actor name leaves the system, or when a tick
+// finds nothing to route to.
+type Schedule struct {
+	ActorName string
+
+	system *ActorSystem
+	cancel chan struct{}
+	once   sync.Once
+	// sending is held while a tick is sent
+	sending sync.Mutex
+}
+
+// Cancel waits for a tick being sent, no message is sent once it returned.
+// So it must not be called by the target while a tick is blocked on its full
+// mailbox.
+func (schedule *Schedule) Cancel() {
+	schedule.stop()
+	schedule.sending.Lock()
+	schedule.sending.Unlock()
+	schedule.system.forgetSchedule(schedule)
+}
+
+func (schedule *Schedule) stop() {
+	schedule.once.Do(func() {
+		close(schedule.cancel)
+	})
+}
+
+// fire sends a tick unless cancelled, and tells whether to go on
+func (schedule *Schedule) fire(event interface{}) bool {
+	schedule.sending.Lock()
+	defer schedule.sending.Unlock()
+	select {
+	case <-schedule.cancel:
+		return false
+	default:
+	}
+
+	system := schedule.system
+	if err := system.Request(schedule.ActorName, event); err != nil {
+		if _, err := system.route(schedule.ActorName, event); err != nil {
+			// the target is gone, its dead letter is the last one
+			return false
+		}
+	}
+	return true
+}
+
+func (system *ActorSystem) RequestAfter(actorName string, event interface{}, delay time.Duration) *Schedule {
+	fired := false
+	return system.schedule(actorName, event, func(now time.Time) (time.Time, bool) {
+		if fired {
+			return now, false
+		}
+		fired = true
+		return now.Add(delay), true
+	})
+}
+
+func (system *ActorSystem) RequestEvery(actorName string, event interface{}, interval time.Duration) *Schedule {
+	start := time.Now()
+	count := 0
+	return system.schedule(actorName, event, func(now time.Time) (time.Time, bool) {
+		// counted from start, so slow delivery doesn't drift the schedule
+		count++
+		return start.Add(time.Duration(count) * interval), true
+	})
+}
+
+// RequestCron takes a five fields cron spec, or descriptors like @hourly
+func (system *ActorSystem) RequestCron(actorName string, event interface{}, spec string) (*Schedule, error) {
+	cron, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	return system.schedule(actorName, event, cron.next), nil
+}
+
+func (system *ActorSystem) schedule(actorName string, event interface{}, next func(now time.Time) (time.Time, bool)) *Schedule {
+	schedule := &Schedule{
+		ActorName: actorName,
+		system:    system,
+		cancel:    make(chan struct{}),
+	}
+
+	system.lock.Lock()
+	if _, ok := system.schedules[actorName]; !ok {
+		system.schedules[actorName] = make(map[*Schedule]struct{})
+	}
+	system.schedules[actorName][schedule] = struct{}{}
+	system.lock.Unlock()
+
+	go func() {
+		defer system.forgetSchedule(schedule)
+		for {
+			at, ok := next(time.Now())
+			if !ok {
+				return
+			}
+
+			timer := time.NewTimer(time.Until(at))
+			select {
+			case <-schedule.cancel:
+				timer.Stop()
+				return
+			case <-timer.C:
+				if !schedule.fire(event) {
+					return
+				}
+			}
+		}
+	}()
+	return schedule
+}
+
+func (system *ActorSystem) forgetSchedule(schedule *Schedule) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	if schedules, ok := system.schedules[schedule.ActorName]; ok {
+		delete(schedules, schedule)
+		if len(schedules) == 0 {
+			delete(system.schedules, schedule.ActorName)
+		}
+	}
+}
+
+// cancelSchedules requires system.lock held
+func (system *ActorSystem) cancelSchedules(actorName string) {
+	for schedule := range system.schedules[actorName] {
+		// not waiting for a tick under system.lock, which sending it may need
+		schedule.stop()
+	}
+	delete(system.schedules, actorName)
+}
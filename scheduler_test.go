@@ -0,0 +1,146 @@
+package goactor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCron(t *testing.T) {
+	base := time.Date(2024, time.January, 31, 10, 7, 30, 0, time.UTC)
+	cases := []struct {
+		spec   string
+		expect time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * *", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1,3", time.Date(2024, time.February, 5, 2, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		cron, err := parseCron(c.spec)
+		if err != nil {
+			t.Errorf("unable to parse %s: %v", c.spec, err)
+			continue
+		}
+		if next, ok := cron.next(base); !ok || !next.Equal(c.expect) {
+			t.Errorf("%s: expect %v, got %v", c.spec, c.expect, next)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * * * mon", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("invalid spec %s accepted", spec)
+		}
+	}
+
+	if cron, _ := parseCron("0 0 30 2 *"); cron != nil {
+		if _, ok := cron.next(base); ok {
+			t.Error("never matching spec matched")
+		}
+	}
+}
+
+func TestRequestAfterAndEvery(t *testing.T) {
+	system := NewDefaultActorSystem()
+	once := &slowActor{0, new(int32), new(int32)}
+	every := &slowActor{0, new(int32), new(int32)}
+	system.AddActor("once", once)
+	system.AddActor("every", every)
+	defer system.Shutdown()
+
+	system.RequestAfter("once", 0, time.Duration(10)*time.Millisecond)
+	schedule := system.RequestEvery("every", 0, time.Duration(10)*time.Millisecond)
+
+	time.Sleep(time.Duration(55) * time.Millisecond)
+	schedule.Cancel()
+	if handled := atomic.LoadInt32(once.handled); handled != 1 {
+		t.Errorf("expect 1 delayed request, got %d", handled)
+	}
+
+	handled := atomic.LoadInt32(every.handled)
+	if handled < 3 {
+		t.Errorf("expect at least 3 periodic requests, got %d", handled)
+	}
+	time.Sleep(time.Duration(30) * time.Millisecond)
+	if atomic.LoadInt32(every.handled) != handled {
+		t.Error("cancelled schedule still delivering")
+	}
+}
+
+func TestScheduleCancelledOnRemove(t *testing.T) {
+	system := NewDefaultActorSystem()
+	processor := &recordDeadLetterProcessor{make(chan interface{}, 10)}
+	system.deadLetterProcessor = processor
+	actor := &slowActor{0, new(int32), new(int32)}
+	system.AddActor("every", actor)
+	defer system.Shutdown()
+
+	system.RequestEvery("every", 0, time.Duration(10)*time.Millisecond)
+	time.Sleep(time.Duration(25) * time.Millisecond)
+	system.RemoveActor("every", actor)
+
+	// a moment for a tick already fired to settle
+	time.Sleep(time.Duration(5) * time.Millisecond)
+	for len(processor.events) > 0 {
+		<-processor.events
+	}
+
+	time.Sleep(time.Duration(30) * time.Millisecond)
+	if len(processor.events) != 0 {
+		t.Error("schedule still running after actor removed")
+	}
+	if len(system.schedules) != 0 {
+		t.Error("schedule not forgotten after actor removed")
+	}
+}
+
+func TestScheduleCancelWaitsForTick(t *testing.T) {
+	system := NewDefaultActorSystem()
+	actor := newGateActor()
+	system.AddActor("bounded", actor, WithMailbox(1, OVERFLOW_BLOCK))
+	defer system.Shutdown()
+
+	fillMailbox(t, system, 1)
+	schedule := system.RequestAfter("bounded", 2, time.Millisecond)
+	// the tick is now blocked on the full mailbox
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	cancelled := make(chan struct{})
+	go func() {
+		schedule.Cancel()
+		close(cancelled)
+	}()
+	select {
+	case <-cancelled:
+		t.Fatal("cancel returned while a tick was being sent")
+	case <-time.After(time.Duration(20) * time.Millisecond):
+	}
+
+	if received := collect(actor, 3); received[2] != 2 {
+		t.Errorf("tick lost, got %v", received)
+	}
+	<-cancelled
+}
+
+func TestScheduleStopsWithoutTarget(t *testing.T) {
+	system := NewDefaultActorSystem()
+	processor := &recordDeadLetterProcessor{make(chan interface{}, 10)}
+	system.deadLetterProcessor = processor
+	defer system.Shutdown()
+
+	system.RequestEvery("nobody", 0, time.Duration(5)*time.Millisecond)
+	time.Sleep(time.Duration(40) * time.Millisecond)
+	if len(processor.events) != 1 {
+		t.Errorf("expect a single dead letter, got %d", len(processor.events))
+	}
+	system.lock.RLock()
+	defer system.lock.RUnlock()
+	if len(system.schedules) != 0 {
+		t.Error("schedule kept without target")
+	}
+}
@@ -0,0 +1,255 @@
+package goactor
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Serializer turns values into bytes and back. Unmarshal makes a value of
+// typ, or a generic one for a nil typ if the format describes itself.
+type Serializer interface {
+	Name() string
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte, typ reflect.Type) (interface{}, error)
+}
+
+type JSONSerializer struct {
+}
+
+func (serializer *JSONSerializer) Name() string {
+	return "json"
+}
+
+func (serializer *JSONSerializer) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (serializer *JSONSerializer) Unmarshal(data []byte, typ reflect.Type) (interface{}, error) {
+	if typ == nil {
+		var value interface{}
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+	target := reflect.New(typ)
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		return nil, err
+	}
+	return target.Elem().Interface(), nil
+}
+
+type GobSerializer struct {
+}
+
+func (serializer *GobSerializer) Name() string {
+	return "gob"
+}
+
+func (serializer *GobSerializer) Marshal(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (serializer *GobSerializer) Unmarshal(data []byte, typ reflect.Type) (interface{}, error) {
+	if typ == nil {
+		return nil, errors.New("gob needs the type to decode")
+	}
+	target := reflect.New(typ)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(target.Interface()); err != nil {
+		return nil, err
+	}
+	return target.Elem().Interface(), nil
+}
+
+// Payload is a serialized value, with the manifest naming its type and the
+// serializer which wrote it
+type Payload struct {
+	Manifest   string
+	Serializer string
+	Data       []byte
+}
+
+type UnknownTypeError struct {
+	Type reflect.Type
+}
+
+func (err *UnknownTypeError) Error() string {
+	return fmt.Sprintf("no serializer registered for type %v", err.Type)
+}
+
+type UnknownManifestError struct {
+	Manifest string
+}
+
+func (err *UnknownManifestError) Error() string {
+	return fmt.Sprintf("no type registered for manifest \"%s\"", err.Manifest)
+}
+
+type serialization struct {
+	manifest   string
+	typ        reflect.Type
+	serializer Serializer
+}
+
+// SerializerRegistry picks the serializer of a value by its Go type. A
+// value of an unregistered type goes to the fallback serializer if any, and
+// is decoded as a generic value by a registry which doesn't know its
+// manifest, provided the fallback format describes itself (json, msgpack).
+type SerializerRegistry struct {
+	types       map[reflect.Type]*serialization
+	manifests   map[string]*serialization
+	serializers map[string]Serializer
+	fallback    Serializer
+	lock        *sync.RWMutex
+}
+
+func NewSerializerRegistry() *SerializerRegistry {
+	registry := &SerializerRegistry{
+		types:       make(map[reflect.Type]*serialization),
+		manifests:   make(map[string]*serialization),
+		serializers: make(map[string]Serializer),
+		lock:        &sync.RWMutex{},
+	}
+
+	builtin := &JSONSerializer{}
+	registry.AddSerializer(&GobSerializer{})
+	registry.AddSerializer(&MsgPackSerializer{})
+	for _, value := range []interface{}{
+		"", false, []byte(nil),
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		[]string(nil), []interface{}(nil), map[string]interface{}(nil),
+	} {
+		registry.Register(value, builtin)
+	}
+	return registry
+}
+
+// manifestOf names a type by its package path, so manifests of different
+// packages never clash
+func manifestOf(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		return "*" + manifestOf(typ.Elem())
+	}
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		return typ.PkgPath() + "." + typ.Name()
+	}
+	return typ.String()
+}
+
+// AddSerializer makes payloads of unknown manifests written by serializer
+// decodable as generic values, once a fallback is set
+func (registry *SerializerRegistry) AddSerializer(serializer Serializer) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.serializers[serializer.Name()] = serializer
+}
+
+// Register serializes values of the type of value with serializer
+func (registry *SerializerRegistry) Register(value interface{}, serializer Serializer) {
+	typ := reflect.TypeOf(value)
+	registry.RegisterManifest(value, manifestOf(typ), serializer)
+}
+
+// RegisterManifest is Register with a manifest of choice, which must be the
+// same on every system exchanging the type
+func (registry *SerializerRegistry) RegisterManifest(value interface{}, manifest string, serializer Serializer) {
+	entry := &serialization{manifest, reflect.TypeOf(value), serializer}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.types[entry.typ] = entry
+	registry.manifests[manifest] = entry
+	registry.serializers[serializer.Name()] = serializer
+}
+
+// SetFallback serializes values of unregistered types, nil to fail them
+func (registry *SerializerRegistry) SetFallback(serializer Serializer) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.fallback = serializer
+	if serializer != nil {
+		registry.serializers[serializer.Name()] = serializer
+	}
+}
+
+func (registry *SerializerRegistry) Serialize(value interface{}) (*Payload, error) {
+	if value == nil {
+		return &Payload{}, nil
+	}
+
+	typ := reflect.TypeOf(value)
+	registry.lock.RLock()
+	entry, ok := registry.types[typ]
+	fallback := registry.fallback
+	registry.lock.RUnlock()
+
+	if !ok {
+		if fallback == nil {
+			return nil, &UnknownTypeError{typ}
+		}
+		entry = &serialization{manifestOf(typ), typ, fallback}
+	}
+
+	data, err := entry.serializer.Marshal(value)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s serializer failed on %v: %v", entry.serializer.Name(), typ, err))
+	}
+	return &Payload{entry.manifest, entry.serializer.Name(), data}, nil
+}
+
+// Deserialize decodes a registered manifest with the serializer registered
+// for it only, a payload claiming another one is rejected. An unknown
+// manifest is decoded generically if the registry has a fallback.
+func (registry *SerializerRegistry) Deserialize(payload *Payload) (interface{}, error) {
+	if payload.Manifest == "" {
+		return nil, nil
+	}
+
+	registry.lock.RLock()
+	entry, ok := registry.manifests[payload.Manifest]
+	serializer := registry.serializers[payload.Serializer]
+	fallback := registry.fallback
+	registry.lock.RUnlock()
+
+	if ok {
+		if payload.Serializer != entry.serializer.Name() {
+			return nil, errors.New(fmt.Sprintf("manifest \"%s\" is serialized with %s, not %s", payload.Manifest, entry.serializer.Name(), payload.Serializer))
+		}
+		return entry.serializer.Unmarshal(payload.Data, entry.typ)
+	}
+	if fallback == nil {
+		return nil, &UnknownManifestError{payload.Manifest}
+	}
+	if serializer == nil {
+		return nil, errors.New(fmt.Sprintf("unknown serializer \"%s\" for manifest \"%s\"", payload.Serializer, payload.Manifest))
+	}
+	return serializer.Unmarshal(payload.Data, nil)
+}
+
+// DefaultSerializers is the registry of systems not given another one
+var DefaultSerializers = NewSerializerRegistry()
+
+func RegisterSerializer(value interface{}, serializer Serializer) {
+	DefaultSerializers.Register(value, serializer)
+}
+
+func (system *ActorSystem) SetSerializers(registry *SerializerRegistry) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	system.serializers = registry
+}
+
+func (system *ActorSystem) serializerRegistry() *SerializerRegistry {
+	system.lock.RLock()
+	defer system.lock.RUnlock()
+	return system.serializers
+}
@@ -0,0 +1,179 @@
+package goactor
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type serializedOrder struct {
+	ID       int64
+	Customer string
+	Items    []string
+	Prices   map[string]float64
+	Note     *string
+	Secret   string `msgpack:"-"`
+	Renamed  uint16 `msgpack:"r"`
+	internal int
+}
+
+type unregisteredEvent struct {
+	Name string
+}
+
+func TestSerializerRoundTrip(t *testing.T) {
+	note := "fragile"
+	order := &serializedOrder{
+		ID:       -42,
+		Customer: "alice",
+		Items:    []string{"book", "pen"},
+		Prices:   map[string]float64{"book": 12.5, "pen": 1},
+		Note:     &note,
+		Renamed:  65535,
+	}
+
+	for _, serializer := range []Serializer{&JSONSerializer{}, &GobSerializer{}, &MsgPackSerializer{}} {
+		registry := NewSerializerRegistry()
+		registry.Register(&serializedOrder{}, serializer)
+
+		payload, err := registry.Serialize(order)
+		if err != nil {
+			t.Fatalf("%s: serialize failed: %v", serializer.Name(), err)
+		}
+		if payload.Manifest != "*github.com/xxpxxxxp/goactor.serializedOrder" || payload.Serializer != serializer.Name() {
+			t.Errorf("%s: unexpected payload %s %s", serializer.Name(), payload.Manifest, payload.Serializer)
+		}
+
+		decoded, err := registry.Deserialize(payload)
+		if err != nil {
+			t.Fatalf("%s: deserialize failed: %v", serializer.Name(), err)
+		}
+		if !reflect.DeepEqual(decoded, order) {
+			t.Errorf("%s: expect %+v, got %+v", serializer.Name(), order, decoded)
+		}
+	}
+}
+
+func TestMsgPackValues(t *testing.T) {
+	serializer := &MsgPackSerializer{}
+	values := []interface{}{
+		int64(0), int64(127), int64(128), int64(-32), int64(-33), int64(math.MinInt64), int64(math.MaxInt64),
+		uint64(math.MaxUint64), true, false, 3.25, float32(1.5),
+		"", string(make([]byte, 40)), string(make([]byte, 70000)), []byte{1, 2, 3},
+		[]int{1, 2, 3}, make([]string, 20), map[string]int{"a": 1}, [2]int{4, 5},
+	}
+
+	for _, value := range values {
+		data, err := serializer.Marshal(value)
+		if err != nil {
+			t.Fatalf("marshal %T failed: %v", value, err)
+		}
+		decoded, err := serializer.Unmarshal(data, reflect.TypeOf(value))
+		if err != nil {
+			t.Fatalf("unmarshal %T failed: %v", value, err)
+		}
+		if !reflect.DeepEqual(decoded, value) {
+			t.Errorf("expect %v, got %v", value, decoded)
+		}
+	}
+
+	// the wire format of well known values
+	if data, _ := serializer.Marshal(map[string]interface{}{"a": []interface{}{1, "b", nil}}); !reflect.DeepEqual(data, []byte{0x81, 0xa1, 'a', 0x93, 0x01, 0xa1, 'b', 0xc0}) {
+		t.Errorf("unexpected encoding % x", data)
+	}
+	if _, err := serializer.Unmarshal([]byte{0x92, 0x01}, nil); err == nil {
+		t.Error("expect truncated data to fail")
+	}
+	if _, err := serializer.Unmarshal([]byte{0xcd, 0x01, 0x00}, reflect.TypeOf(int8(0))); err == nil {
+		t.Error("expect overflow to fail")
+	}
+}
+
+func TestSerializerUnknownType(t *testing.T) {
+	registry := NewSerializerRegistry()
+
+	_, err := registry.Serialize(&unregisteredEvent{"x"})
+	var unknown *UnknownTypeError
+	if !errors.As(err, &unknown) || unknown.Type != reflect.TypeOf(&unregisteredEvent{}) {
+		t.Errorf("expect unknown type error, got %v", err)
+	}
+
+	_, err = registry.Deserialize(&Payload{Manifest: "nowhere.Event", Serializer: "json", Data: []byte("{}")})
+	var manifest *UnknownManifestError
+	if !errors.As(err, &manifest) || manifest.Manifest != "nowhere.Event" {
+		t.Errorf("expect unknown manifest error, got %v", err)
+	}
+}
+
+func TestSerializerFallback(t *testing.T) {
+	sender := NewSerializerRegistry()
+	sender.SetFallback(&MsgPackSerializer{})
+	payload, err := sender.Serialize(&unregisteredEvent{"x"})
+	if err != nil {
+		t.Fatalf("expect fallback, got %v", err)
+	}
+
+	receiver := NewSerializerRegistry()
+	if _, err := receiver.Deserialize(payload); err == nil {
+		t.Error("expect a receiver without fallback to fail")
+	}
+	receiver.SetFallback(&JSONSerializer{})
+	decoded, err := receiver.Deserialize(payload)
+	if err != nil || !reflect.DeepEqual(decoded, map[string]interface{}{"Name": "x"}) {
+		t.Errorf("expect a generic value, got %v, %v", decoded, err)
+	}
+
+	// a registered manifest decodes with its own serializer only
+	receiver.Register(&unregisteredEvent{}, &JSONSerializer{})
+	if _, err := receiver.Deserialize(payload); err == nil {
+		t.Error("expect a msgpack payload of a json manifest to fail")
+	}
+	receiver.Register(&unregisteredEvent{}, &MsgPackSerializer{})
+	decoded, err = receiver.Deserialize(payload)
+	if err != nil || !reflect.DeepEqual(decoded, &unregisteredEvent{"x"}) {
+		t.Errorf("expect the registered type, got %v, %v", decoded, err)
+	}
+
+	if payload, _ := sender.Serialize(nil); payload.Manifest != "" {
+		t.Error("expect nil to have no manifest")
+	}
+}
+
+func TestMsgPackHostileInput(t *testing.T) {
+	registry := NewSerializerRegistry()
+
+	// a map keyed by an array can't be hashed
+	_, err := registry.Deserialize(&Payload{Manifest: "[]interface {}", Serializer: "msgpack", Data: []byte{0x91, 0x81, 0x91, 0x01, 0x01}})
+	if err == nil {
+		t.Error("expect an unhashable key to fail")
+	}
+	registry.Register([]interface{}(nil), &MsgPackSerializer{})
+	if _, err := registry.Deserialize(&Payload{Manifest: "[]interface {}", Serializer: "msgpack", Data: []byte{0x91, 0x81, 0x91, 0x01, 0x01}}); err == nil {
+		t.Error("expect an unhashable key to fail")
+	}
+	if _, err := registry.Deserialize(&Payload{Manifest: "[]interface {}", Serializer: "msgpack", Data: []byte{0x91, 0x81, 0xc4, 0x00, 0x01}}); err == nil {
+		t.Error("expect a bin key to fail")
+	}
+	if _, err := (&MsgPackSerializer{}).Unmarshal([]byte{0x81, 0x80, 0x01}, reflect.TypeOf(map[interface{}]int{})); err == nil {
+		t.Error("expect a map key to fail")
+	}
+
+	// the peer doesn't pick the decoder of a registered manifest
+	if _, err := registry.Deserialize(&Payload{Manifest: "string", Serializer: "gob", Data: []byte{}}); err == nil {
+		t.Error("expect a serializer other than the registered one to fail")
+	}
+
+	deep := make([]byte, 1<<20)
+	for i := range deep {
+		deep[i] = 0x91
+	}
+	if _, err := registry.Deserialize(&Payload{Manifest: "[]interface {}", Serializer: "msgpack", Data: deep}); err == nil {
+		t.Error("expect deep nesting to fail")
+	}
+	nested := append(bytes.Repeat([]byte{0x91}, msgpackMaxDepth-1), 0x01)
+	if _, err := registry.Deserialize(&Payload{Manifest: "[]interface {}", Serializer: "msgpack", Data: nested}); err != nil {
+		t.Errorf("expect nesting within the limit to decode, got %v", err)
+	}
+}
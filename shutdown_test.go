@@ -0,0 +1,127 @@
+package goactor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type slowActor struct {
+	delay    time.Duration
+	handled  *int32
+	pullouts *int32
+}
+
+func (actor *slowActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *slowActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	time.Sleep(actor.delay)
+	atomic.AddInt32(actor.handled, 1)
+	return event
+}
+
+func (actor *slowActor) OnPullout(system *ActorSystem) {
+	atomic.AddInt32(actor.pullouts, 1)
+}
+
+func TestShutdownGracefully(t *testing.T) {
+	system := NewDefaultActorSystem()
+	actor := &slowActor{time.Millisecond, new(int32), new(int32)}
+	system.AddActor("slow", actor)
+	system.AddActor("slow", actor)
+
+	for i := 0; i < 20; i++ {
+		system.Request("slow", i)
+	}
+
+	report, err := system.ShutdownGracefully(context.Background())
+	if err != nil || len(report.Unfinished) != 0 || len(report.Discarded) != 0 {
+		t.Errorf("unexpected report %+v, %v", report, err)
+	}
+	if handled := atomic.LoadInt32(actor.handled); handled != 20 {
+		t.Errorf("mailbox not drained, handled %d", handled)
+	}
+	if pullouts := atomic.LoadInt32(actor.pullouts); pullouts != 2 {
+		t.Errorf("expect 2 pullouts, got %d", pullouts)
+	}
+	if err := system.Request("slow", 0); err == nil {
+		t.Error("actor still in system after shutdown")
+	}
+	if _, err := system.AddActor("late", new(mockActor)); err != ErrSystemShutdown {
+		t.Errorf("expect the system closed for good, got %v", err)
+	}
+}
+
+func TestShutdownGracefullyDeadline(t *testing.T) {
+	system := NewDefaultActorSystem()
+	processor := &recordDeadLetterProcessor{make(chan interface{}, 100)}
+	system.deadLetterProcessor = processor
+	actor := &slowActor{time.Duration(20) * time.Millisecond, new(int32), new(int32)}
+	system.AddActor("slow", actor)
+
+	for i := 0; i < 10; i++ {
+		system.Request("slow", i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(30)*time.Millisecond)
+	defer cancel()
+	stopping := make(chan *ShutdownReport)
+	go func() {
+		report, _ := system.ShutdownGracefully(ctx)
+		stopping <- report
+	}()
+
+	// a moment for the shutdown to start
+	time.Sleep(time.Duration(5) * time.Millisecond)
+	if err := system.Request("slow", 10); err != ErrSystemShutdown {
+		t.Errorf("expect shutdown error, got %v", err)
+	}
+	<-processor.events
+
+	report := <-stopping
+	if len(report.Unfinished) != 1 || report.Unfinished[0] != "slow" {
+		t.Errorf("expect slow unfinished, got %v", report.Unfinished)
+	}
+
+	// the rest goes to dead letters once the current event is done
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt32(actor.pullouts) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("actor not pulled out after shutdown")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	discarded := 10 - int(atomic.LoadInt32(actor.handled))
+	if discarded == 0 {
+		t.Error("expect events discarded")
+	}
+	for i := 0; i < discarded; i++ {
+		select {
+		case <-processor.events:
+		case <-time.After(time.Second):
+			t.Fatalf("expect %d discarded events in dead letters, got %d", discarded, i)
+		}
+	}
+}
+
+func TestShutdownGracefullyStuck(t *testing.T) {
+	system := NewDefaultActorSystem()
+	actor := newGateActor()
+	system.AddActor("stuck", actor)
+	system.Request("stuck", 0)
+	defer close(actor.gate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(50)*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	report, err := system.ShutdownGracefully(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expect deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Duration(500)*time.Millisecond {
+		t.Errorf("shutdown waited %v for a stuck actor", elapsed)
+	}
+	if len(report.Unfinished) != 1 || report.Unfinished[0] != "stuck" {
+		t.Errorf("expect stuck unfinished, got %v", report.Unfinished)
+	}
+}
@@ -0,0 +1,30 @@
+package standard
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/xxpxxxxp/goactor"
+)
+
+func init() {
+	RegisterFactory("http", func(config map[string]interface{}) ActorInterface {
+		actor := NewDefaultHttpActor()
+		if timeout, ok := config["timeout_ms"].(float64); ok {
+			actor.Client = &http.Client{Timeout: time.Duration(timeout) * time.Millisecond}
+		}
+		return actor
+	})
+
+	RegisterFactory("broadcast", func(config map[string]interface{}) ActorInterface {
+		broadcaster := &BroadcastActor{}
+		if group, ok := config["group"].([]interface{}); ok {
+			for _, name := range group {
+				if s, ok := name.(string); ok {
+					broadcaster.BroadCastGroup = append(broadcaster.BroadCastGroup, s)
+				}
+			}
+		}
+		return broadcaster
+	})
+}
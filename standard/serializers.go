@@ -0,0 +1,177 @@
+package standard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+
+	. "github.com/xxpxxxxp/goactor"
+)
+
+func init() {
+	httpCodec := &httpSerializer{}
+	RegisterSerializer(&HttpRequest{}, httpCodec)
+	RegisterSerializer([]*HttpRequest(nil), httpCodec)
+	RegisterSerializer(&HttpResponse{}, httpCodec)
+	RegisterSerializer([]*HttpResponse(nil), httpCodec)
+
+	jsonCodec := &JSONSerializer{}
+	for _, request := range []interface{}{
+		CreateNodeRequest{},
+		BatchNodesOperationRequest{},
+		WatchPathRequest{},
+		RemoveNodeRequest(""),
+		RmrRequest(""),
+		GetNodeDataRequest(""),
+		SetNodeDataRequest{},
+		GetSubNodesRequest(""),
+	} {
+		RegisterSerializer(request, jsonCodec)
+	}
+}
+
+type httpRequestWire struct {
+	Url     string
+	Method  string
+	Body    []byte
+	Headers []*Parameter
+}
+
+type httpResponseWire struct {
+	Body  []byte
+	Error string
+}
+
+// httpSerializer reads the body of a request, and carries the error of a
+// response as its message
+type httpSerializer struct {
+	codec MsgPackSerializer
+}
+
+func (serializer *httpSerializer) Name() string {
+	return "standard.http"
+}
+
+func (serializer *httpSerializer) Marshal(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case *HttpRequest:
+		wire, err := toRequestWire(v)
+		if err != nil {
+			return nil, err
+		}
+		return serializer.codec.Marshal(wire)
+	case []*HttpRequest:
+		wires := make([]*httpRequestWire, len(v))
+		for i, request := range v {
+			wire, err := toRequestWire(request)
+			if err != nil {
+				return nil, err
+			}
+			wires[i] = wire
+		}
+		return serializer.codec.Marshal(wires)
+	case *HttpResponse:
+		return serializer.codec.Marshal(toResponseWire(v))
+	case []*HttpResponse:
+		wires := make([]*httpResponseWire, len(v))
+		for i, response := range v {
+			wires[i] = toResponseWire(response)
+		}
+		return serializer.codec.Marshal(wires)
+	}
+	return nil, errors.New(fmt.Sprintf("http serializer doesn't support %T", value))
+}
+
+func (serializer *httpSerializer) Unmarshal(data []byte, typ reflect.Type) (interface{}, error) {
+	switch typ {
+	case reflect.TypeOf(&HttpRequest{}):
+		wire, err := serializer.codec.Unmarshal(data, reflect.TypeOf(&httpRequestWire{}))
+		if err != nil {
+			return nil, err
+		}
+		return fromRequestWire(wire.(*httpRequestWire)), nil
+	case reflect.TypeOf([]*HttpRequest(nil)):
+		wires, err := serializer.codec.Unmarshal(data, reflect.TypeOf([]*httpRequestWire(nil)))
+		if err != nil {
+			return nil, err
+		}
+		requests := make([]*HttpRequest, len(wires.([]*httpRequestWire)))
+		for i, wire := range wires.([]*httpRequestWire) {
+			requests[i] = fromRequestWire(wire)
+		}
+		return requests, nil
+	case reflect.TypeOf(&HttpResponse{}):
+		wire, err := serializer.codec.Unmarshal(data, reflect.TypeOf(&httpResponseWire{}))
+		if err != nil {
+			return nil, err
+		}
+		return fromResponseWire(wire.(*httpResponseWire)), nil
+	case reflect.TypeOf([]*HttpResponse(nil)):
+		wires, err := serializer.codec.Unmarshal(data, reflect.TypeOf([]*httpResponseWire(nil)))
+		if err != nil {
+			return nil, err
+		}
+		responses := make([]*HttpResponse, len(wires.([]*httpResponseWire)))
+		for i, wire := range wires.([]*httpResponseWire) {
+			responses[i] = fromResponseWire(wire)
+		}
+		return responses, nil
+	}
+	return nil, errors.New(fmt.Sprintf("http serializer doesn't support %v", typ))
+}
+
+func toRequestWire(request *HttpRequest) (*httpRequestWire, error) {
+	if request == nil {
+		return nil, nil
+	}
+
+	wire := &httpRequestWire{Url: request.Url, Method: request.Method, Headers: request.Headers}
+	if request.Body != nil {
+		body, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			return nil, err
+		}
+		// the body has been consumed, put it back for local use
+		request.Body = bytes.NewReader(body)
+		wire.Body = body
+	}
+	return wire, nil
+}
+
+func fromRequestWire(wire *httpRequestWire) *HttpRequest {
+	if wire == nil {
+		return nil
+	}
+
+	request := &HttpRequest{Url: wire.Url, Method: wire.Method, Headers: wire.Headers}
+	if wire.Body != nil {
+		request.Body = bytes.NewReader(wire.Body)
+	}
+	return request
+}
+
+func toResponseWire(response *HttpResponse) *httpResponseWire {
+	if response == nil {
+		return nil
+	}
+
+	wire := &httpResponseWire{Body: response.Body}
+	if response.Error != nil {
+		wire.Error = response.Error.Error()
+	}
+	return wire
+}
+
+func fromResponseWire(wire *httpResponseWire) *HttpResponse {
+	if wire == nil {
+		return nil
+	}
+
+	response := &HttpResponse{Body: wire.Body}
+	if wire.Error != "" {
+		response.Error = errors.New(wire.Error)
+	}
+	return response
+}
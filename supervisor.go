@@ -0,0 +1,105 @@
+package goactor
+
+import (
+	"fmt"
+	"time"
+)
+
+type SupervisorDirective int
+
+const (
+	DIRECTIVE_RESUME SupervisorDirective = iota
+	DIRECTIVE_RESTART
+	DIRECTIVE_STOP
+	DIRECTIVE_ESCALATE
+)
+
+type SupervisorStrategy struct {
+	// nil decider always restarts
+	Decider func(actorName string, reason interface{}) SupervisorDirective
+	// negative MaxRestarts means unlimited, zero Within means forever
+	MaxRestarts int
+	Within      time.Duration
+}
+
+func NewRestartStrategy(maxRestarts int, within time.Duration) *SupervisorStrategy {
+	return &SupervisorStrategy{
+		Decider:     nil,
+		MaxRestarts: maxRestarts,
+		Within:      within,
+	}
+}
+
+func NewDirectiveStrategy(directive SupervisorDirective) *SupervisorStrategy {
+	return &SupervisorStrategy{
+		Decider: func(actorName string, reason interface{}) SupervisorDirective {
+			return directive
+		},
+		MaxRestarts: -1,
+		Within:      0,
+	}
+}
+
+func (strategy *SupervisorStrategy) decide(actorName string, reason interface{}) SupervisorDirective {
+	if strategy.Decider == nil {
+		return DIRECTIVE_RESTART
+	}
+	return strategy.Decider(actorName, reason)
+}
+
+type ActorCrashError struct {
+	ActorName string
+	Reason    interface{}
+}
+
+func (err *ActorCrashError) Error() string {
+	return fmt.Sprintf("actor %s crashed: %v", err.ActorName, err.Reason)
+}
+
+type CrashReporter interface {
+	Report(actorName string, event interface{}, reason interface{}, directive SupervisorDirective)
+}
+
+type ConsoleCrashReporter struct {
+}
+
+func (reporter *ConsoleCrashReporter) Report(actorName string, event interface{}, reason interface{}, directive SupervisorDirective) {
+	fmt.Printf("Actor \"%s\" crashed on event \"%+v\": %v, directive %d\n", actorName, event, reason, directive)
+}
+
+func NewConsoleCrashReporter() *ConsoleCrashReporter {
+	return &ConsoleCrashReporter{}
+}
+
+// failedResponse carries an error to a Require caller, so it can't be
+// mistaken for an error value returned by Receive itself
+type failedResponse struct {
+	err error
+}
+
+type restartWindow struct {
+	restarts []time.Time
+}
+
+func (window *restartWindow) allow(strategy *SupervisorStrategy) bool {
+	if strategy.MaxRestarts < 0 {
+		return true
+	}
+
+	now := time.Now()
+	if strategy.Within > 0 {
+		kept := window.restarts[:0]
+		for _, t := range window.restarts {
+			if now.Sub(t) < strategy.Within {
+				kept = append(kept, t)
+			}
+		}
+		window.restarts = kept
+	}
+
+	if len(window.restarts) >= strategy.MaxRestarts {
+		return false
+	}
+	window.restarts = append(window.restarts, now)
+	return true
+}
@@ -0,0 +1,100 @@
+package goactor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type panicActor struct {
+	plugins  *int32
+	pullouts *int32
+}
+
+func (actor *panicActor) OnPlugin(system *ActorSystem) {
+	atomic.AddInt32(actor.plugins, 1)
+}
+
+func (actor *panicActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	if event == "panic" {
+		panic("boom")
+	}
+	return event
+}
+
+func (actor *panicActor) OnPullout(system *ActorSystem) {
+	atomic.AddInt32(actor.pullouts, 1)
+}
+
+type silentCrashReporter struct {
+	directives chan SupervisorDirective
+}
+
+func (reporter *silentCrashReporter) Report(actorName string, event interface{}, reason interface{}, directive SupervisorDirective) {
+	reporter.directives <- directive
+}
+
+func newSupervisedSystem(name string, strategy *SupervisorStrategy) (*ActorSystem, *panicActor, *silentCrashReporter) {
+	system := NewDefaultActorSystem()
+	reporter := &silentCrashReporter{make(chan SupervisorDirective, 10)}
+	system.SetCrashReporter(reporter)
+	system.Supervise(name, strategy)
+
+	actor := &panicActor{new(int32), new(int32)}
+	system.AddActor(name, actor)
+	return system, actor, reporter
+}
+
+func TestSupervisorCrashError(t *testing.T) {
+	system, _, _ := newSupervisedSystem("crash", NewDirectiveStrategy(DIRECTIVE_RESUME))
+	defer system.Shutdown()
+
+	_, err := system.Require("crash", "panic", 1000)
+	if crash, ok := err.(*ActorCrashError); !ok || crash.ActorName != "crash" || crash.Reason != "boom" {
+		t.Errorf("expect crash error, got %v", err)
+	}
+
+	if rst, err := system.Require("crash", "ok", 1000); rst != "ok" || err != nil {
+		t.Error("resumed actor doesn't respond")
+	}
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	system, actor, reporter := newSupervisedSystem("restart", NewRestartStrategy(2, time.Minute))
+	defer system.Shutdown()
+
+	for i, expect := range []SupervisorDirective{DIRECTIVE_RESTART, DIRECTIVE_RESTART, DIRECTIVE_STOP} {
+		system.Require("restart", "panic", 1000)
+		if directive := <-reporter.directives; directive != expect {
+			t.Errorf("crash %d: expect directive %d, got %d", i, expect, directive)
+		}
+	}
+
+	// a moment for the actor deconstruct
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	if plugins := atomic.LoadInt32(actor.plugins); plugins != 3 {
+		t.Errorf("expect 3 plugins, got %d", plugins)
+	}
+	if pullouts := atomic.LoadInt32(actor.pullouts); pullouts != 3 {
+		t.Errorf("expect 3 pullouts, got %d", pullouts)
+	}
+	if _, err := system.Require("restart", "ok", 100); err == nil {
+		t.Error("stopped actor still in system")
+	}
+}
+
+func TestSupervisorEscalate(t *testing.T) {
+	system, _, reporter := newSupervisedSystem("escalate", NewDirectiveStrategy(DIRECTIVE_ESCALATE))
+	system.SetDefaultStrategy(NewDirectiveStrategy(DIRECTIVE_STOP))
+	system.AddActor("other", new(mockActor))
+	defer system.Shutdown()
+
+	system.Require("escalate", "panic", 1000)
+	if directive := <-reporter.directives; directive != DIRECTIVE_STOP {
+		t.Errorf("escalation not handled by default strategy, got %d", directive)
+	}
+	if rst, err := system.Require("other", "ok", 1000); rst != "ok" || err != nil {
+		t.Error("other actor affected by stopped one")
+	}
+}
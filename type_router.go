@@ -0,0 +1,271 @@
+package goactor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// BY_TYPE addresses whichever actor accepts the event's type, when the
+// system routes with a TypeRouter
+const BY_TYPE = "@type"
+
+// TypeAcceptor declares the event types an actor handles
+type TypeAcceptor interface {
+	Accepts() []reflect.Type
+}
+
+// TypeRouter routes BY_TYPE to an instance accepting the event's dynamic
+// type, exact types are preferred over interfaces it implements. Other
+// names are left to the wrapped router.
+type TypeRouter struct {
+	named    Router
+	balancer Balancer
+}
+
+func NewTypeRouter(named Router, balancer Balancer) *TypeRouter {
+	return &TypeRouter{
+		named:    named,
+		balancer: balancer,
+	}
+}
+
+func NewTypeWithRandomBalancerRouter() *TypeRouter {
+	return NewTypeRouter(NewFullQualifiedNameWithRandomBalancerRouter(), NewRandomBalancer())
+}
+
+func (router *TypeRouter) Route(actorName string, actors map[string][]*innerActor) (actor *innerActor, err error) {
+	return router.RouteEvent(actorName, nil, actors)
+}
+
+func (router *TypeRouter) RouteRegistry(actorName string, event interface{}, registry *actorRegistry) (actor *innerActor, err error) {
+	if actorName != BY_TYPE {
+		if named, ok := router.named.(RegistryRouter); ok {
+			return named.RouteRegistry(actorName, event, registry)
+		}
+		return router.RouteEvent(actorName, event, registry.all())
+	}
+
+	if event == nil {
+		return nil, errors.New("Unable to route nil event by type")
+	}
+	eventType := reflect.TypeOf(event)
+	if actors := registry.byType(eventType); len(actors) > 0 {
+		return choose(router.balancer, BY_TYPE, event, actors), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unable to find actor accepts %v", eventType))
+}
+
+func (router *TypeRouter) RouteEvent(actorName string, event interface{}, actors map[string][]*innerActor) (actor *innerActor, err error) {
+	if actorName != BY_TYPE {
+		if named, ok := router.named.(EventRouter); ok {
+			return named.RouteEvent(actorName, event, actors)
+		}
+		return router.named.Route(actorName, actors)
+	}
+
+	if event == nil {
+		return nil, errors.New("Unable to route nil event by type")
+	}
+
+	eventType := reflect.TypeOf(event)
+	var exact, implemented []*innerActor
+	names := make([]string, 0, len(actors))
+	for name := range actors {
+		names = append(names, name)
+	}
+	// keep instances in a stable order for stateful balancers
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, instance := range actors[name] {
+			for _, accepted := range instance.acceptedTypes() {
+				if accepted == eventType {
+					exact = append(exact, instance)
+					break
+				} else if accepted.Kind() == reflect.Interface && eventType.Implements(accepted) {
+					implemented = append(implemented, instance)
+					break
+				}
+			}
+		}
+	}
+
+	if len(exact) > 0 {
+		return choose(router.balancer, BY_TYPE, event, exact), nil
+	}
+	if len(implemented) > 0 {
+		return choose(router.balancer, BY_TYPE, event, implemented), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unable to find actor accepts %v", eventType))
+}
+
+func (router *TypeRouter) forget(actorName string) {
+	forget(router.named, actorName)
+}
+
+func (system *ActorSystem) RequestByType(event interface{}) error {
+	return system.Request(BY_TYPE, event)
+}
+
+func (system *ActorSystem) RequireByType(event interface{}, timeoutInMilliSec int) (rst interface{}, err error) {
+	return system.Require(BY_TYPE, event, timeoutInMilliSec)
+}
+
+func (actor *innerActor) accept(actorImpl ActorInterface) {
+	var accepts []reflect.Type
+	if acceptor, ok := actorImpl.(TypeAcceptor); ok {
+		accepts = acceptor.Accepts()
+	}
+	actor.accepts.Store(accepts)
+}
+
+func (actor *innerActor) acceptedTypes() []reflect.Type {
+	accepts, _ := actor.accepts.Load().([]reflect.Type)
+	return accepts
+}
+
+// typeIndex maps accepted types to the instances accepting them, in the order
+// they were added. Like in actorRegistry, slices are replaced, never changed.
+type typeIndex struct {
+	types sync.Map // reflect.Type -> []*innerActor
+	// interfaces lists the accepted interface types, as a []reflect.Type
+	interfaces atomic.Value
+	// resolved caches the instances an event type goes to, per registry version
+	resolved sync.Map // reflect.Type -> *resolvedType
+
+	// guarded by system.lock
+	indexed    map[*innerActor][]reflect.Type
+	implements map[reflect.Type]int
+}
+
+type resolvedType struct {
+	version uint64
+	actors  []*innerActor
+}
+
+func newTypeIndex() *typeIndex {
+	return &typeIndex{
+		indexed:    make(map[*innerActor][]reflect.Type),
+		implements: make(map[reflect.Type]int),
+	}
+}
+
+// byType is the instances accepting eventType exactly, or else those
+// accepting an interface it implements
+func (registry *actorRegistry) byType(eventType reflect.Type) []*innerActor {
+	version := atomic.LoadUint64(&registry.version)
+	if cached, ok := registry.types.resolved.Load(eventType); ok && cached.(*resolvedType).version == version {
+		return cached.(*resolvedType).actors
+	}
+
+	actors := registry.types.lookup(eventType)
+	if len(actors) == 0 {
+		interfaces, _ := registry.types.interfaces.Load().([]reflect.Type)
+		seen := make(map[*innerActor]bool)
+		for _, accepted := range interfaces {
+			if !eventType.Implements(accepted) {
+				continue
+			}
+			for _, actor := range registry.types.lookup(accepted) {
+				if !seen[actor] {
+					seen[actor] = true
+					actors = append(actors, actor)
+				}
+			}
+		}
+	}
+	registry.types.resolved.Store(eventType, &resolvedType{version, actors})
+	return actors
+}
+
+func (index *typeIndex) lookup(accepted reflect.Type) []*innerActor {
+	actors, _ := index.types.Load(accepted)
+	instances, _ := actors.([]*innerActor)
+	return instances
+}
+
+// update requires system.lock held, it drops instances of old missing from
+// actors and adds those of actors not indexed yet
+func (index *typeIndex) update(old []*innerActor, actors []*innerActor) {
+	current := make(map[*innerActor]bool, len(actors))
+	for _, actor := range actors {
+		current[actor] = true
+		if _, ok := index.indexed[actor]; !ok {
+			index.add(actor)
+		}
+	}
+	for _, actor := range old {
+		if !current[actor] {
+			index.remove(actor)
+		}
+	}
+}
+
+func (index *typeIndex) add(actor *innerActor) {
+	var types []reflect.Type
+	for _, accepted := range actor.acceptedTypes() {
+		if contains(types, accepted) {
+			continue
+		}
+		types = append(types, accepted)
+
+		actors := index.lookup(accepted)
+		index.types.Store(accepted, append(actors[:len(actors):len(actors)], actor))
+		if accepted.Kind() == reflect.Interface {
+			if index.implements[accepted]++; index.implements[accepted] == 1 {
+				interfaces, _ := index.interfaces.Load().([]reflect.Type)
+				index.interfaces.Store(append(interfaces[:len(interfaces):len(interfaces)], accepted))
+			}
+		}
+	}
+	index.indexed[actor] = types
+}
+
+func (index *typeIndex) remove(actor *innerActor) {
+	types, ok := index.indexed[actor]
+	if !ok {
+		return
+	}
+	delete(index.indexed, actor)
+
+	for _, accepted := range types {
+		actors := index.lookup(accepted)
+		for i, instance := range actors {
+			if instance != actor {
+				continue
+			}
+			if len(actors) == 1 {
+				index.types.Delete(accepted)
+			} else {
+				index.types.Store(accepted, without(actors, i))
+			}
+			break
+		}
+		if accepted.Kind() == reflect.Interface {
+			if index.implements[accepted]--; index.implements[accepted] == 0 {
+				delete(index.implements, accepted)
+				interfaces, _ := index.interfaces.Load().([]reflect.Type)
+				rest := make([]reflect.Type, 0, len(interfaces))
+				for _, known := range interfaces {
+					if known != accepted {
+						rest = append(rest, known)
+					}
+				}
+				index.interfaces.Store(rest)
+			}
+		}
+	}
+}
+
+func contains(types []reflect.Type, target reflect.Type) bool {
+	for _, known := range types {
+		if known == target {
+			return true
+		}
+	}
+	return false
+}
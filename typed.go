@@ -0,0 +1,107 @@
+package goactor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+type RequestTypeError struct {
+	ActorName string
+	Expected  reflect.Type
+	Actual    reflect.Type
+}
+
+func (err *RequestTypeError) Error() string {
+	return fmt.Sprintf("actor %s expects request of type %v, got %v", err.ActorName, err.Expected, err.Actual)
+}
+
+type ResponseTypeError struct {
+	ActorName string
+	Expected  reflect.Type
+	Actual    reflect.Type
+}
+
+func (err *ResponseTypeError) Error() string {
+	return fmt.Sprintf("actor %s responded %v, expected %v", err.ActorName, err.Actual, err.Expected)
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func castResponse[Resp any](actorName string, rst interface{}, err error) (Resp, error) {
+	var zero Resp
+	if err != nil {
+		return zero, err
+	}
+
+	expected := typeOf[Resp]()
+	if rst == nil {
+		switch expected.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return zero, nil
+		}
+		return zero, &ResponseTypeError{actorName, expected, nil}
+	}
+
+	if resp, ok := rst.(Resp); ok {
+		return resp, nil
+	}
+	return zero, &ResponseTypeError{actorName, expected, reflect.TypeOf(rst)}
+}
+
+func Ask[Req, Resp any](system *ActorSystem, actorName string, request Req, timeoutInMilliSec int) (Resp, error) {
+	rst, err := system.Require(actorName, request, timeoutInMilliSec)
+	return castResponse[Resp](actorName, rst, err)
+}
+
+func AskCtx[Req, Resp any](ctx context.Context, system *ActorSystem, actorName string, request Req) (Resp, error) {
+	rst, err := system.RequireCtx(ctx, actorName, request)
+	return castResponse[Resp](actorName, rst, err)
+}
+
+func Tell[Req any](system *ActorSystem, actorName string, request Req) error {
+	return system.Request(actorName, request)
+}
+
+// TypedActor adapts a typed handler to ActorInterface. Errors returned by
+// the handler, and requests of the wrong type, reach Require callers as err.
+type TypedActor[Req, Resp any] struct {
+	Name    string
+	Handler func(system *ActorSystem, eventType EventType, request Req) (Resp, error)
+	Plugin  func(system *ActorSystem)
+	Pullout func(system *ActorSystem)
+}
+
+func NewTypedActor[Req, Resp any](name string, handler func(system *ActorSystem, eventType EventType, request Req) (Resp, error)) *TypedActor[Req, Resp] {
+	return &TypedActor[Req, Resp]{
+		Name:    name,
+		Handler: handler,
+	}
+}
+
+func (actor *TypedActor[Req, Resp]) OnPlugin(system *ActorSystem) {
+	if actor.Plugin != nil {
+		actor.Plugin(system)
+	}
+}
+
+func (actor *TypedActor[Req, Resp]) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	request, ok := event.(Req)
+	if !ok {
+		return &failedResponse{&RequestTypeError{actor.Name, typeOf[Req](), reflect.TypeOf(event)}}
+	}
+
+	resp, err := actor.Handler(system, eventType, request)
+	if err != nil {
+		return &failedResponse{err}
+	}
+	return resp
+}
+
+func (actor *TypedActor[Req, Resp]) OnPullout(system *ActorSystem) {
+	if actor.Pullout != nil {
+		actor.Pullout(system)
+	}
+}
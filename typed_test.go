@@ -0,0 +1,57 @@
+package goactor
+
+import (
+	"errors"
+	"testing"
+)
+
+type square int
+
+func TestTypedActor(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("square", NewTypedActor("square", func(system *ActorSystem, eventType EventType, request square) (int, error) {
+		if request < 0 {
+			return 0, errors.New("negative")
+		}
+		return int(request * request), nil
+	}))
+	defer system.Shutdown()
+
+	if rst, err := Ask[square, int](system, "square", 3, 1000); rst != 9 || err != nil {
+		t.Errorf("expect 9, got %v, %v", rst, err)
+	}
+
+	if _, err := Ask[square, int](system, "square", -1, 1000); err == nil || err.Error() != "negative" {
+		t.Errorf("handler error not returned, got %v", err)
+	}
+
+	if _, err := Ask[int, int](system, "square", 3, 1000); err == nil {
+		t.Error("wrong request type accepted")
+	} else if _, ok := err.(*RequestTypeError); !ok {
+		t.Errorf("expect request type error, got %v", err)
+	}
+
+	if _, err := Ask[square, string](system, "square", 3, 1000); err == nil {
+		t.Error("wrong response type accepted")
+	} else if typeErr, ok := err.(*ResponseTypeError); !ok || typeErr.Actual.Kind().String() != "int" {
+		t.Errorf("expect response type error, got %v", err)
+	}
+
+	if err := Tell[square](system, "square", 3); err != nil {
+		t.Errorf("tell failed: %v", err)
+	}
+}
+
+func TestAskNilResponse(t *testing.T) {
+	system := NewDefaultActorSystem()
+	system.AddActor("echo", new(mockActor))
+	defer system.Shutdown()
+
+	if rst, err := Ask[interface{}, *int](system, "echo", nil, 1000); rst != nil || err != nil {
+		t.Errorf("nil should be a valid pointer response, got %v, %v", rst, err)
+	}
+
+	if _, err := Ask[interface{}, int](system, "echo", nil, 1000); err == nil {
+		t.Error("nil accepted as int response")
+	}
+}
@@ -0,0 +1,91 @@
+package goactor
+
+type TerminationReason int
+
+const (
+	TERMINATED_REMOVED TerminationReason = iota
+	TERMINATED_SHUTDOWN
+	TERMINATED_CRASHED
+)
+
+// Terminated is delivered to every instance of the watchers when an instance
+// of ActorName stops, Last tells no instance of the name is running any more
+type Terminated struct {
+	ActorName string
+	Reason    TerminationReason
+	Last      bool
+}
+
+// Watch notifies watcher each time an instance of watched stops
+func (system *ActorSystem) Watch(watcher string, watched string) {
+	system.watch(watcher, watched, false)
+}
+
+// WatchLast notifies watcher only when the last instance of watched stops
+func (system *ActorSystem) WatchLast(watcher string, watched string) {
+	system.watch(watcher, watched, true)
+}
+
+func (system *ActorSystem) watch(watcher string, watched string, lastOnly bool) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	if _, ok := system.watchers[watched]; !ok {
+		system.watchers[watched] = make(map[string]bool)
+	}
+	system.watchers[watched][watcher] = lastOnly
+}
+
+func (system *ActorSystem) Unwatch(watcher string, watched string) {
+	system.lock.Lock()
+	defer system.lock.Unlock()
+	if watchers, ok := system.watchers[watched]; ok {
+		delete(watchers, watcher)
+		if len(watchers) == 0 {
+			delete(system.watchers, watched)
+		}
+	}
+}
+
+// unwatchAll requires system.lock held, it drops the watches of a watcher no
+// instance is left of
+func (system *ActorSystem) unwatchAll(watcher string) {
+	for watched, watchers := range system.watchers {
+		delete(watchers, watcher)
+		if len(watchers) == 0 {
+			delete(system.watchers, watched)
+		}
+	}
+}
+
+func (system *ActorSystem) terminated(actor *innerActor, reason TerminationReason) {
+	system.stream.forget(actor)
+
+	system.lock.Lock()
+	system.alive[actor.name]--
+	last := system.alive[actor.name] <= 0
+	if last {
+		delete(system.alive, actor.name)
+		delete(system.draining, actor.name)
+	}
+
+	var notify []*innerActor
+	for watcher, lastOnly := range system.watchers[actor.name] {
+		if last || !lastOnly {
+			targets := system.registry.lookup(watcher)
+			if len(targets) == 0 {
+				targets = system.draining[watcher]
+			}
+			notify = append(notify, targets...)
+		}
+	}
+	system.lock.Unlock()
+
+	terminated := &Terminated{actor.name, reason, last}
+	// a watcher gone as well doesn't care, no dead letter for it
+	for _, target := range notify {
+		target.push(&Event{
+			event:        terminated,
+			responseChan: nil,
+		})
+	}
+}
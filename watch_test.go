@@ -0,0 +1,140 @@
+package goactor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type watcherActor struct {
+	terminated chan *Terminated
+}
+
+func (actor *watcherActor) OnPlugin(system *ActorSystem) {}
+
+func (actor *watcherActor) Receive(system *ActorSystem, eventType EventType, event interface{}) interface{} {
+	switch typed := event.(type) {
+	case *Terminated:
+		actor.terminated <- typed
+	case chan struct{}:
+		<-typed
+	}
+	return nil
+}
+
+func (actor *watcherActor) OnPullout(system *ActorSystem) {}
+
+func expectTerminated(t *testing.T, actor *watcherActor, expect Terminated) {
+	select {
+	case terminated := <-actor.terminated:
+		if *terminated != expect {
+			t.Errorf("expect %+v, got %+v", expect, *terminated)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("%+v never delivered", expect)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	system := NewDefaultActorSystem()
+	anyWatcher := &watcherActor{make(chan *Terminated, 10)}
+	lastWatcher := &watcherActor{make(chan *Terminated, 10)}
+	system.AddActor("any", anyWatcher)
+	system.AddActor("last", lastWatcher)
+	defer system.Shutdown()
+
+	first, second := new(mockActor), new(mockActor)
+	system.AddActor("watched", first)
+	system.AddActor("watched", second)
+	system.Watch("any", "watched")
+	system.WatchLast("last", "watched")
+
+	system.RemoveActor("watched", first)
+	expectTerminated(t, anyWatcher, Terminated{"watched", TERMINATED_REMOVED, false})
+
+	system.RemoveActor("watched", second)
+	expectTerminated(t, anyWatcher, Terminated{"watched", TERMINATED_REMOVED, true})
+	expectTerminated(t, lastWatcher, Terminated{"watched", TERMINATED_REMOVED, true})
+
+	if len(lastWatcher.terminated) != 0 {
+		t.Error("last watcher notified on non-last instance")
+	}
+
+	system.Unwatch("any", "watched")
+	system.AddActor("watched", first)
+	system.RemoveActor("watched", first)
+	expectTerminated(t, lastWatcher, Terminated{"watched", TERMINATED_REMOVED, true})
+	if len(anyWatcher.terminated) != 0 {
+		t.Error("unwatched actor still notified")
+	}
+}
+
+func TestWatchCrashed(t *testing.T) {
+	system, _, reporter := newSupervisedSystem("crash", NewDirectiveStrategy(DIRECTIVE_STOP))
+	watcher := &watcherActor{make(chan *Terminated, 10)}
+	system.AddActor("watcher", watcher)
+	system.Watch("watcher", "crash")
+	defer system.Shutdown()
+
+	system.Request("crash", "panic")
+	<-reporter.directives
+	expectTerminated(t, watcher, Terminated{"crash", TERMINATED_CRASHED, true})
+}
+
+func TestWatchEveryInstance(t *testing.T) {
+	system := NewDefaultActorSystem()
+	first := &watcherActor{make(chan *Terminated, 10)}
+	second := &watcherActor{make(chan *Terminated, 10)}
+	system.AddActor("watcher", first)
+	system.AddActor("watcher", second)
+	defer system.Shutdown()
+
+	watched := new(mockActor)
+	system.AddActor("watched", watched)
+	system.Watch("watcher", "watched")
+	system.RemoveActor("watched", watched)
+	expectTerminated(t, first, Terminated{"watched", TERMINATED_REMOVED, true})
+	expectTerminated(t, second, Terminated{"watched", TERMINATED_REMOVED, true})
+}
+
+func TestWatcherRemoved(t *testing.T) {
+	system := NewDefaultActorSystem()
+	watcher := &watcherActor{make(chan *Terminated, 10)}
+	system.AddActor("watcher", watcher)
+	defer system.Shutdown()
+
+	system.Watch("watcher", "a")
+	system.Watch("watcher", "b")
+	system.RemoveActor("watcher", watcher)
+
+	system.lock.RLock()
+	defer system.lock.RUnlock()
+	if len(system.watchers) != 0 {
+		t.Errorf("watches of a removed watcher kept: %v", system.watchers)
+	}
+}
+
+func TestWatchShutdownGracefully(t *testing.T) {
+	system := NewDefaultActorSystem()
+	watcher := &watcherActor{make(chan *Terminated, 10)}
+	system.AddActor("watcher", watcher)
+	system.AddActor("watched", new(mockActor))
+	system.Watch("watcher", "watched")
+	watched := system.registry.lookup("watched")[0]
+
+	// the watcher is still draining its mailbox when watched stops
+	gate := make(chan struct{})
+	system.Request("watcher", gate)
+	stopping := make(chan error)
+	go func() {
+		_, err := system.ShutdownGracefully(context.Background())
+		stopping <- err
+	}()
+	<-watched.stopped
+	close(gate)
+
+	expectTerminated(t, watcher, Terminated{"watched", TERMINATED_SHUTDOWN, true})
+	if err := <-stopping; err != nil {
+		t.Errorf("unexpected shutdown error %v", err)
+	}
+}